@@ -27,6 +27,17 @@ const (
 	ActChosenCarousel
 	ActStatus
 	ActIdentify
+	ActPreviousWallpaper
+	ActNextWallpaper
+	ActUndoWallpaper
+	ActFavorite
+	ActUnfavorite
+	ActRateWallpaper
+	ActBanWallpaper
+	ActTagWallpaper
+	ActUntagWallpaper
+	ActReindex
+	ActFindDuplicates
 )
 
 /* ----------------------------------------------------------------
@@ -34,29 +45,51 @@ const (
  *-----------------------------------------------------------------*/
 
 var toString = map[Action]string{
-	ActNone:             "ActNone",
-	ActDefaultWallpaper: "ActDefaultWallpaper",
-	ActAnyWallpaper:     "ActAnyWallpaper",
-	ActLockCarousel:     "ActLockCarousel",
-	ActUnlockCarousel:   "ActUnlockCarousel",
-	ActChosenFile:       "ActChosenFile",
-	ActChosenCategory:   "ActChosenCategory",
-	ActChosenCarousel:   "ActChosenCarousel",
-	ActStatus:           "ActStatus",
-	ActIdentify:         "ActIdentify",
+	ActNone:              "ActNone",
+	ActDefaultWallpaper:  "ActDefaultWallpaper",
+	ActAnyWallpaper:      "ActAnyWallpaper",
+	ActLockCarousel:      "ActLockCarousel",
+	ActUnlockCarousel:    "ActUnlockCarousel",
+	ActChosenFile:        "ActChosenFile",
+	ActChosenCategory:    "ActChosenCategory",
+	ActChosenCarousel:    "ActChosenCarousel",
+	ActStatus:            "ActStatus",
+	ActIdentify:          "ActIdentify",
+	ActPreviousWallpaper: "ActPreviousWallpaper",
+	ActNextWallpaper:     "ActNextWallpaper",
+	ActUndoWallpaper:     "ActUndoWallpaper",
+	ActFavorite:          "ActFavorite",
+	ActUnfavorite:        "ActUnfavorite",
+	ActRateWallpaper:     "ActRateWallpaper",
+	ActBanWallpaper:      "ActBanWallpaper",
+	ActTagWallpaper:      "ActTagWallpaper",
+	ActUntagWallpaper:    "ActUntagWallpaper",
+	ActReindex:           "ActReindex",
+	ActFindDuplicates:    "ActFindDuplicates",
 }
 
 var toID = map[string]Action{
-	"ActNone":             ActNone,
-	"ActDefaultWallpaper": ActDefaultWallpaper,
-	"ActAnyWallpaper":     ActAnyWallpaper,
-	"ActLockCarousel":     ActLockCarousel,
-	"ActUnlockCarousel":   ActUnlockCarousel,
-	"ActChosenFile":       ActChosenFile,
-	"ActChosenCategory":   ActChosenCategory,
-	"ActChosenCarousel":   ActChosenCarousel,
-	"ActStatus":           ActStatus,
-	"ActIdentify":         ActIdentify,
+	"ActNone":              ActNone,
+	"ActDefaultWallpaper":  ActDefaultWallpaper,
+	"ActAnyWallpaper":      ActAnyWallpaper,
+	"ActLockCarousel":      ActLockCarousel,
+	"ActUnlockCarousel":    ActUnlockCarousel,
+	"ActChosenFile":        ActChosenFile,
+	"ActChosenCategory":    ActChosenCategory,
+	"ActChosenCarousel":    ActChosenCarousel,
+	"ActStatus":            ActStatus,
+	"ActIdentify":          ActIdentify,
+	"ActPreviousWallpaper": ActPreviousWallpaper,
+	"ActNextWallpaper":     ActNextWallpaper,
+	"ActUndoWallpaper":     ActUndoWallpaper,
+	"ActFavorite":          ActFavorite,
+	"ActUnfavorite":        ActUnfavorite,
+	"ActRateWallpaper":     ActRateWallpaper,
+	"ActBanWallpaper":      ActBanWallpaper,
+	"ActTagWallpaper":      ActTagWallpaper,
+	"ActUntagWallpaper":    ActUntagWallpaper,
+	"ActReindex":           ActReindex,
+	"ActFindDuplicates":    ActFindDuplicates,
 }
 
 /* ----------------------------------------------------------------
@@ -93,6 +126,19 @@ func (s *Action) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+/**
+ * Whether the action puts up another wallpaper, those that do may
+ * conflict when scheduled at the same time.
+ */
+func (s Action) ChangesWallpaper() bool {
+	switch s {
+	case ActDefaultWallpaper, ActAnyWallpaper, ActChosenFile, ActChosenCategory, ActChosenCarousel,
+		ActPreviousWallpaper, ActNextWallpaper, ActUndoWallpaper:
+		return true
+	}
+	return false
+}
+
 func (c Action) Parse(v string) (Action, error) {
 	if v, ok := toID[v]; !ok {
 		return ActDefaultWallpaper, fmt.Errorf("invalid enum '%s'", v)
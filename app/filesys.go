@@ -25,3 +25,15 @@ func FileExists(filename string) bool {
 	_, err := os.Stat(filename)
 	return !errors.Is(err, os.ErrNotExist)
 }
+
+/**
+ * Per-user cache directory for derived files (variants, palettes, etc.)
+ * falling back to the temporary directory when the OS doesn't have one.
+ */
+func GetUserCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return GetUserTempDir()
+	}
+	return dir
+}
@@ -9,6 +9,11 @@
  *-----------------------------------------------------------------*/
 package app
 
+import (
+	"os"
+	"path"
+)
+
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
  *-----------------------------------------------------------------*/
@@ -16,3 +21,18 @@ package app
 func GetUserTempDir() string {
 	return "/tmp"
 }
+
+/**
+ * Per-user state directory as per the XDG Base Directory spec.
+ */
+func GetUserStateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return GetUserTempDir()
+	}
+	return path.Join(home, ".local", "state")
+}
@@ -22,3 +22,10 @@ func GetUserTempDir() string {
 	}
 	return dir
 }
+
+/**
+ * Per-user state directory (%LocalAppData%).
+ */
+func GetUserStateDir() string {
+	return GetUserTempDir()
+}
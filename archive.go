@@ -0,0 +1,340 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Wallpaper packs: .zip, .tar & .tar.gz archives as categories.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	ARCHIVE_SEP        = "#" // pack.zip#autumn/leaves.jpg
+	CACHE_ARCHIVE_DIR  = "archives"
+	ARCHIVE_MAX_MEMBER = 256 << 20 // refuse absurdly large members
+)
+
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type archiveReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+/**
+ * Called for every regular file in an archive.
+ * @param content (func) its contents, only valid during the call
+ */
+type archiveVisitor func(member string, size int64, modTime time.Time, content func() (io.ReadCloser, error)) error
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (r *archiveReader) Close() error {
+	var errs []error
+	for idx := len(r.closers) - 1; idx >= 0; idx-- {
+		errs = append(errs, r.closers[idx].Close())
+	}
+	return errors.Join(errs...)
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the file name is that of a supported wallpaper pack.
+ */
+func isArchive(filename string) bool {
+	return archiveExtension(filename) != ""
+}
+
+/**
+ * Split the name of a wallpaper inside a pack into the archive and
+ * the member names.
+ */
+func splitArchivePath(filename string) (archive, member string, ok bool) {
+	lower := strings.ToLower(filename)
+	for _, ext := range archiveExtensions {
+		if at := strings.Index(lower, ext+ARCHIVE_SEP); at != -1 {
+			cut := at + len(ext)
+			return filename[:cut], filename[cut+len(ARCHIVE_SEP):], true
+		}
+	}
+	return "", "", false
+}
+
+/**
+ * The wallpapers in a pack. Only members not among the known entries
+ * or changed since (size, modification time) are examined.
+ */
+func scanArchive(archive string, known map[string]CatalogEntry) ([]CatalogEntry, error) {
+	entries := make([]CatalogEntry, 0)
+	err := walkArchive(archive, func(member string, size int64, modTime time.Time, content func() (io.ReadCloser, error)) error {
+		if !isWallpaperFile(path.Base(member)) || size > ARCHIVE_MAX_MEMBER {
+			return nil
+		}
+
+		filename := archive + ARCHIVE_SEP + member
+		entry, seen := known[filename]
+		if !seen || entry.Size != size || entry.ModTime != modTime.UnixNano() {
+			rc, err := content()
+			if err != nil {
+				return err
+			}
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+
+			entry = newArchiveEntry(filename, modTime, data)
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries, err
+}
+
+/**
+ * Extract a wallpaper from its pack into the cache, unless already
+ * there. Files outside packs are returned as they are.
+ * @returns (string) name of a regular file with the wallpaper
+ */
+func extractWallpaper(filename string) (string, error) {
+	archive, member, ok := splitArchivePath(filename)
+	if !ok {
+		return filename, nil
+	}
+
+	fi, err := os.Stat(archive)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := getAppCacheDir(CACHE_ARCHIVE_DIR)
+	if err != nil {
+		return "", err
+	}
+
+	absName, _ := filepath.Abs(archive)
+	key := fmt.Sprintf("%s|%d|%d|%s", absName, fi.Size(), fi.ModTime().UnixNano(), member)
+	sum := md5.Sum([]byte(key))
+	extracted := path.Join(cacheDir, hex.EncodeToString(sum[:])+strings.ToLower(path.Ext(member)))
+	if FileExists(extracted) {
+		return extracted, nil
+	}
+
+	rc, err := openArchiveMember(archive, member)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	fdOut, err := os.Create(extracted + ".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(fdOut, io.LimitReader(rc, ARCHIVE_MAX_MEMBER))
+	if cerr := fdOut.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(extracted + ".tmp")
+		return "", err
+	}
+
+	return extracted, os.Rename(extracted+".tmp", extracted)
+}
+
+/**
+ * Open a wallpaper file, which may be inside a pack.
+ */
+func openWallpaper(filename string) (io.ReadCloser, error) {
+	if archive, member, ok := splitArchivePath(filename); ok {
+		return openArchiveMember(archive, member)
+	}
+	return os.Open(filename)
+}
+
+func openArchiveMember(archive, member string) (io.ReadCloser, error) {
+	if archiveExtension(archive) == ".zip" {
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, err
+		}
+
+		// by the name as listed, which may not be a valid fs path (./a.jpg)
+		idx := slices.IndexFunc(zr.File, func(file *zip.File) bool {
+			return file.Name == member
+		})
+		if idx == -1 {
+			zr.Close()
+			return nil, fmt.Errorf("%s in %s: %w", member, archive, os.ErrNotExist)
+		}
+
+		fd, err := zr.File[idx].Open()
+		if err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return &archiveReader{fd, []io.Closer{zr, fd}}, nil
+	}
+
+	fd, tr, err := openTar(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			fd.Close()
+			if err == io.EOF {
+				err = os.ErrNotExist
+			}
+			return nil, fmt.Errorf("%s in %s: %w", member, archive, err)
+		}
+
+		if header.Name == member {
+			return &archiveReader{tr, []io.Closer{fd}}, nil
+		}
+	}
+}
+
+/**
+ * Visit every regular file in a pack.
+ */
+func walkArchive(archive string, visit archiveVisitor) error {
+	if archiveExtension(archive) == ".zip" {
+		return walkZip(archive, visit)
+	}
+	return walkTar(archive, visit)
+}
+
+func walkZip(archive string, visit archiveVisitor) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, file := range zr.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+
+		if err = visit(file.Name, int64(file.UncompressedSize64), file.Modified, file.Open); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkTar(archive string, visit archiveVisitor) error {
+	fd, tr, err := openTar(archive)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		content := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+		if err = visit(header.Name, header.Size, header.ModTime, content); err != nil {
+			return err
+		}
+	}
+}
+
+/**
+ * Open a (compressed) tar archive.
+ * @returns the closer of the whole thing and the tar reader
+ */
+func openTar(archive string) (io.Closer, *tar.Reader, error) {
+	fd, err := os.Open(archive)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if archiveExtension(archive) == ".tar" {
+		return fd, tar.NewReader(fd), nil
+	}
+
+	gz, err := gzip.NewReader(fd)
+	if err != nil {
+		fd.Close()
+		return nil, nil, err
+	}
+	return &archiveReader{gz, []io.Closer{fd, gz}}, tar.NewReader(gz), nil
+}
+
+func archiveExtension(filename string) string {
+	lower := strings.ToLower(filename)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+/**
+ * Everything we want to know about a wallpaper inside a pack.
+ */
+func newArchiveEntry(filename string, modTime time.Time, data []byte) CatalogEntry {
+	sum := md5.Sum(data)
+	head := data[:min(len(data), XMP_SCAN_LIMIT)]
+	entry := CatalogEntry{
+		Path:    filename,
+		Size:    int64(len(data)),
+		ModTime: modTime.UnixNano(),
+		Hash:    hex.EncodeToString(sum[:]),
+		Tags:    normalizeTags(embeddedKeywords(head)),
+		Taken:   exifOf(head).Taken,
+	}
+
+	if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		entry.Width, entry.Height = config.Width, config.Height
+	}
+	return entry
+}
@@ -0,0 +1,633 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Persistent wallpaper catalog so that large collections (or those
+ * on a slow network share) aren't read on every change.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CATALOG_FILE    = "catalog.json"
+	CATALOG_MAX_AGE = 24 * time.Hour // rescan even if the directory didn't change
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type CatalogEntry struct {
+	Path        string       `json:"path"`
+	Size        int64        `json:"size"`
+	ModTime     int64        `json:"mtime"`
+	Width       int          `json:"width,omitempty"` // 0 if it can't be decoded (SVG)
+	Height      int          `json:"height,omitempty"`
+	Hash        string       `json:"hash"`                  // same content hash as the state store
+	Tags        []string     `json:"tags,omitempty"`        // sidecar & embedded keywords
+	Sidecar     int64        `json:"sidecar,omitempty"`     // mtime of the sidecar, 0 without one
+	Taken       time.Time    `json:"taken,omitzero"`        // EXIF date
+	Hashes      *ImageHashes `json:"perceptual,omitempty"`  // computed on demand
+	Undecodable bool         `json:"undecodable,omitempty"` // no perceptual hash, don't try again
+	Group       string       `json:"group,omitempty"`       // near-duplicates share it
+}
+
+/**
+ * A directory as it was when last scanned. It is rescanned when its
+ * modification time changes (files added, removed or renamed) or when
+ * the scan gets too old.
+ */
+type CatalogDir struct {
+	ModTime int64          `json:"mtime"`
+	Scanned time.Time      `json:"scanned"`
+	Entries []CatalogEntry `json:"entries"`
+}
+
+/**
+ * The wallpapers found in the directories we were asked about and what
+ * we know about them.
+ */
+type Catalog struct {
+	Directories map[string]*CatalogDir `json:"directories"`
+	Grouped     *DuplicateOpts         `json:"grouped,omitempty"` // nil when entries changed since
+	file        string
+	dirty       bool
+	byPath      map[string]*CatalogEntry
+}
+
+type CatalogStats struct {
+	Directories int
+	Files       int
+	Bytes       int64
+	Tagged      int
+	Dated       int
+	IndexSize   int64 // size of the catalog file
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Load the catalog from the cache directory. Being a cache, a
+ * missing or unreadable catalog is an empty one.
+ */
+func NewCatalog() (*Catalog, error) {
+	dir, err := getAppCacheDir("")
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{file: path.Join(dir, CATALOG_FILE)}
+	data, err := os.ReadFile(catalog.file)
+	if err == nil {
+		if err = json.Unmarshal(data, catalog); err != nil {
+			log.Printf("rebuilding damaged catalog: %s", err)
+			catalog.Directories = nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if catalog.Directories == nil {
+		catalog.Directories = make(map[string]*CatalogDir)
+	}
+	return catalog, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * All wallpaper files (full path) in a directory.
+ */
+func (c *Catalog) Files(dir string) ([]string, error) {
+	cached, err := c.refresh(dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cached.Entries) == 0 {
+		log.Println("No files found in the directory.")
+		return nil, NewAppErrorf(ErrNoQualifyingWallpaper, "no qualifying wallpaper files").At("carousel")
+	}
+
+	files := make([]string, len(cached.Entries))
+	for idx, entry := range cached.Entries {
+		files[idx] = entry.Path
+	}
+	return files, nil
+}
+
+/**
+ * The wallpapers in the given directories matching a smart category
+ * query. The tags assigned in the state store count as much as those
+ * in the files themselves. Directories that can't be read are logged
+ * and skipped.
+ * @param now (time.Time) the day dates are relative to
+ */
+func (c *Catalog) Query(dirs []string, query *CategoryQuery, store *StateStore, now time.Time) []string {
+	matches := make([]string, 0)
+	for _, dir := range dirs {
+		cached, err := c.refresh(dir, false)
+		if err != nil {
+			log.Printf("catalog skips %s: %s", dir, err)
+			continue
+		}
+
+		for _, entry := range cached.Entries {
+			if !query.MatchDate(entry.Taken, now) {
+				continue
+			}
+
+			tags := entry.Tags
+			record := store.LookupHash(entry.Path, entry.Hash)
+			if record != nil {
+				tags = normalizeTags(slices.Concat(tags, record.Tags))
+			}
+
+			if !MatchTags(tags, query.Tags) {
+				continue
+			}
+
+			if query.MinRating > 0 && (record == nil || record.Rating < query.MinRating) {
+				continue
+			}
+
+			matches = append(matches, entry.Path)
+		}
+	}
+
+	return matches
+}
+
+/**
+ * The content hash of a cataloged file, if we have it.
+ */
+func (c *Catalog) HashOf(filename string) (string, bool) {
+	if entry, ok := c.entries()[filename]; ok && entry.Hash != "" {
+		return entry.Hash, true
+	}
+	return "", false
+}
+
+/**
+ * Rescan the given directories from scratch and forget any other.
+ * Directories that can't be read right now (i.e. an unmounted key
+ * device) keep what we knew about them.
+ */
+func (c *Catalog) Reindex(dirs []string) error {
+	keep := make(map[string]bool)
+	var errs []error
+	for _, dir := range dirs {
+		key := filepath.Clean(dir)
+		keep[key] = true
+		if _, err := c.refresh(dir, true); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for dir := range c.Directories {
+		if !keep[dir] {
+			delete(c.Directories, dir)
+			c.touch()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+/**
+ * Groups of two or more (near-)duplicate wallpapers in the given
+ * directories: identical contents or similar perceptual hashes.
+ * Perceptual hashes not yet in the catalog are computed, which for
+ * a large collection takes a while the first time.
+ */
+func (c *Catalog) Duplicates(dirs []string, opts DuplicateOpts) [][]CatalogEntry {
+	entries := make([]*CatalogEntry, 0)
+	for _, dir := range dirs {
+		cached, err := c.refresh(dir, false)
+		if err != nil {
+			log.Printf("catalog skips %s: %s", dir, err)
+			continue
+		}
+		for idx := range cached.Entries {
+			entries = append(entries, &cached.Entries[idx])
+		}
+	}
+
+	duplicates := make([][]CatalogEntry, 0)
+	for _, cluster := range c.cluster(entries, opts) {
+		if len(cluster) > 1 {
+			group := make([]CatalogEntry, len(cluster))
+			for idx, entry := range cluster {
+				group[idx] = *entry
+			}
+			duplicates = append(duplicates, group)
+		}
+	}
+
+	return duplicates
+}
+
+/**
+ * Reduce a list of wallpapers to one per group of near-duplicates,
+ * the one with the highest resolution. Files not in the catalog are
+ * kept as they are. The groups are only worked out again when the
+ * catalog changed since (or the options did), not on every call.
+ */
+func (c *Catalog) Collapse(files []string, opts DuplicateOpts) []string {
+	if c.Grouped == nil || *c.Grouped != opts {
+		c.Group(opts)
+	}
+
+	byPath := c.entries()
+	best := make(map[string]*CatalogEntry)
+	order := make([]string, 0, len(files))
+	collapsed := make([]string, 0, len(files))
+	for _, file := range files {
+		entry, ok := byPath[file]
+		if !ok {
+			collapsed = append(collapsed, file)
+			continue
+		}
+
+		group := cmp.Or(entry.Group, entry.Path)
+		if current, seen := best[group]; !seen {
+			best[group] = entry
+			order = append(order, group)
+		} else if entry.Width*entry.Height > current.Width*current.Height {
+			best[group] = entry
+		}
+	}
+
+	for _, group := range order {
+		collapsed = append(collapsed, best[group].Path)
+	}
+	return collapsed
+}
+
+/**
+ * Work out the groups of near-duplicates among all cataloged entries
+ * and remember them, together with the perceptual hashes, until the
+ * catalog changes. Takes a while for a large collection the first time.
+ */
+func (c *Catalog) Group(opts DuplicateOpts) {
+	entries := slices.Collect(maps.Values(c.entries()))
+	slices.SortFunc(entries, func(a, b *CatalogEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	for _, cluster := range c.cluster(entries, opts) {
+		group := ""
+		if len(cluster) > 1 {
+			group = cluster[0].Path
+		}
+		for _, entry := range cluster {
+			entry.Group = group
+		}
+	}
+
+	c.Grouped = &opts
+	c.dirty = true
+}
+
+func (c *Catalog) Stats() CatalogStats {
+	stats := CatalogStats{Directories: len(c.Directories)}
+	for _, cached := range c.Directories {
+		for _, entry := range cached.Entries {
+			stats.Files++
+			stats.Bytes += entry.Size
+			if len(entry.Tags) > 0 {
+				stats.Tagged++
+			}
+			if !entry.Taken.IsZero() {
+				stats.Dated++
+			}
+		}
+	}
+
+	if fi, err := os.Stat(c.file); err == nil {
+		stats.IndexSize = fi.Size()
+	}
+	return stats
+}
+
+/**
+ * Save the catalog if anything changed since it was loaded.
+ */
+func (c *Catalog) Save() error {
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(c.file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(c.file+".tmp", c.file); err != nil {
+		return err
+	}
+
+	c.dirty = false
+	return nil
+}
+
+func (s CatalogStats) String() string {
+	return fmt.Sprintf("%d wallpapers (%.1f MB) in %d directories, %d tagged, %d dated. Index %.1f KB",
+		s.Files, float64(s.Bytes)/(1<<20), s.Directories, s.Tagged, s.Dated, float64(s.IndexSize)/(1<<10))
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Bring a directory up to date. Only files that are new or changed
+ * (size, modification time) are examined.
+ * @param force (bool) rescan even if the directory seems unchanged
+ */
+func (c *Catalog) refresh(dir string, force bool) (*CatalogDir, error) {
+	key := filepath.Clean(dir)
+	fi, err := os.Stat(key)
+	if err != nil {
+		fmt.Println("Error reading directory:", err)
+		return nil, err
+	}
+
+	cached, ok := c.Directories[key]
+	if ok && !force && cached.ModTime == fi.ModTime().UnixNano() && time.Since(cached.Scanned) < CATALOG_MAX_AGE && !cached.sidecarsChanged() {
+		return cached, nil
+	}
+
+	known := make(map[string]CatalogEntry)
+	if ok && !force {
+		for _, entry := range cached.Entries {
+			known[entry.Path] = entry
+		}
+	}
+
+	fresh := &CatalogDir{ModTime: fi.ModTime().UnixNano(), Scanned: time.Now()}
+	if isArchive(key) {
+		fresh.Entries, err = scanArchive(key, known)
+	} else {
+		fresh.Entries, err = scanDirectory(key, known)
+	}
+	if err != nil {
+		fmt.Println("Error reading directory:", err)
+		return nil, err
+	}
+
+	c.Directories[key] = fresh
+	c.touch()
+	return fresh, nil
+}
+
+/**
+ * Whether a sidecar of the entries was edited or removed, which
+ * doesn't change the directory. New ones do.
+ */
+func (d *CatalogDir) sidecarsChanged() bool {
+	for idx := range d.Entries {
+		if entry := &d.Entries[idx]; entry.Sidecar != 0 && entry.Sidecar != sidecarTime(entry.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * All cataloged entries by path.
+ */
+func (c *Catalog) entries() map[string]*CatalogEntry {
+	if c.byPath == nil {
+		c.byPath = make(map[string]*CatalogEntry)
+		for _, cached := range c.Directories {
+			for idx := range cached.Entries {
+				c.byPath[cached.Entries[idx].Path] = &cached.Entries[idx]
+			}
+		}
+	}
+	return c.byPath
+}
+
+/**
+ * Group entries with the same content or similar perceptual hashes,
+ * keeping their order.
+ */
+func (c *Catalog) cluster(entries []*CatalogEntry, opts DuplicateOpts) [][]*CatalogEntry {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = DEFAULT_HASH_LIMIT
+	}
+
+	for _, entry := range entries {
+		c.perceptualHashes(entry)
+	}
+
+	// union-find over the entry indexes
+	parent := make([]int, len(entries))
+	for idx := range parent {
+		parent[idx] = idx
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i, a := range entries {
+		for j := i + 1; j < len(entries); j++ {
+			b := entries[j]
+			same := a.Hash != "" && a.Hash == b.Hash
+			if !same && a.Hashes != nil && b.Hashes != nil {
+				same = a.Hashes.Distance(*b.Hashes, opts.Algorithm) <= threshold
+			}
+			if same {
+				parent[root(j)] = root(i)
+			}
+		}
+	}
+
+	clusters := make([][]*CatalogEntry, 0)
+	index := make(map[int]int) // root to cluster
+	for idx, entry := range entries {
+		r := root(idx)
+		if at, ok := index[r]; ok {
+			clusters[at] = append(clusters[at], entry)
+		} else {
+			index[r] = len(clusters)
+			clusters = append(clusters, []*CatalogEntry{entry})
+		}
+	}
+
+	return clusters
+}
+
+/**
+ * Compute the perceptual hashes of an entry unless already known.
+ * Images we can't decode (SVG) have none.
+ */
+func (c *Catalog) perceptualHashes(entry *CatalogEntry) {
+	if entry.Hashes != nil || entry.Undecodable || entry.Width == 0 {
+		return
+	}
+
+	img, err := decodeImageFile(entry.Path)
+	if err != nil {
+		log.Printf("no perceptual hash for %s: %s", entry.Path, err)
+		entry.Undecodable = true // until the file changes
+		c.dirty = true
+		return
+	}
+
+	hashes := NewImageHashes(img)
+	entry.Hashes = &hashes
+	c.dirty = true
+}
+
+func (c *Catalog) touch() {
+	c.dirty = true
+	c.byPath = nil
+	c.Grouped = nil
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Print the size of the catalog as it is, without refreshing it.
+ */
+func PrintCatalogStats() {
+	if catalog, err := NewCatalog(); err == nil {
+		fmt.Println("Catalog:", catalog.Stats())
+	}
+}
+
+/**
+ * Print groups of duplicates, the one selection would keep first.
+ */
+func PrintDuplicates(groups [][]CatalogEntry) {
+	if len(groups) == 0 {
+		fmt.Println("No duplicates found")
+		return
+	}
+
+	for idx, group := range groups {
+		slices.SortStableFunc(group, func(a, b CatalogEntry) int {
+			return b.Width*b.Height - a.Width*a.Height
+		})
+
+		fmt.Printf("Duplicates #%d\n", idx+1)
+		for _, entry := range group {
+			fmt.Printf("\t%5dx%-5d %s\n", entry.Width, entry.Height, entry.Path)
+		}
+	}
+}
+
+/**
+ * The wallpapers in a directory. Only files not among the known
+ * entries or changed since (size, modification time) are examined,
+ * and the tags of those whose sidecar changed.
+ */
+func scanDirectory(dir string, known map[string]CatalogEntry) ([]CatalogEntry, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]CatalogEntry, 0)
+	for _, file := range files {
+		if file.IsDir() || !isWallpaperFile(file.Name()) {
+			continue
+		}
+
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+
+		filename := path.Join(dir, file.Name())
+		entry, seen := known[filename]
+		if !seen || entry.Size != info.Size() || entry.ModTime != info.ModTime().UnixNano() {
+			entry = newCatalogEntry(filename, info)
+		} else if sidecar := sidecarTime(filename); entry.Sidecar != sidecar {
+			entry.Tags, entry.Sidecar = ReadImageTags(filename), sidecar
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+/**
+ * Everything we want to know about a wallpaper file. Failures leave
+ * the corresponding fields empty.
+ */
+func newCatalogEntry(filename string, info os.FileInfo) CatalogEntry {
+	entry := CatalogEntry{
+		Path:    filename,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Tags:    ReadImageTags(filename),
+		Sidecar: sidecarTime(filename),
+		Taken:   ReadExif(filename).Taken,
+	}
+
+	entry.Hash, _ = CalculateMD5(filename)
+	if fd, err := os.Open(filename); err == nil {
+		if config, _, err := image.DecodeConfig(fd); err == nil {
+			entry.Width, entry.Height = config.Width, config.Height
+		}
+		fd.Close()
+	}
+
+	return entry
+}
+
+/**
+ * The modification time of the sidecar of an image, 0 without one.
+ */
+func sidecarTime(filename string) int64 {
+	if fi, err := os.Stat(filename + SIDECAR_EXT); err == nil {
+		return fi.ModTime().UnixNano()
+	}
+	return 0
+}
+
+func isWallpaperFile(name string) bool {
+	if name == DEFAULT_ICON_FILE {
+		return false
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".svg" // @todo globalize
+}
@@ -0,0 +1,200 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Catching up on schedules missed while the machine was asleep or
+ * off.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CATCHUP_NONE   = "none"   // missed is missed
+	CATCHUP_LATEST = "latest" // the last missed run, unless a later one overrides it
+	CATCHUP_ALL    = "all"    // every missed run, in order
+
+	CATCHUP_MAX_AGE   = 31 * 24 * time.Hour // older runs are lost for good
+	CATCHUP_MAX_TICKS = 100000              // more than a month of every-minute ticks
+	CATCHUP_MAX_RUNS  = 100                 // missed runs of a schedule replayed under the all policy
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * A schedule that was due while nothing was checking.
+ */
+type MissedRun struct {
+	Job int // index in the settings
+	At  time.Time
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The catch-up policy of the schedule, its own or that of the options.
+ */
+func (s *Schedule) CatchUpPolicy(opts *Options) string {
+	return cmp.Or(s.CatchUp, opts.CatchUp, CATCHUP_NONE)
+}
+
+func (m MissedRun) String() string {
+	return fmt.Sprintf("job #%d missed at %s", m.Job+1, m.At.Format("2006-01-02 15:04"))
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the runs of the schedule are remembered, by its title.
+ */
+func (s *Schedule) isRecorded(opts *Options) bool {
+	return s.IsInterval() || s.CatchUpPolicy(opts) != CATCHUP_NONE
+}
+
+/**
+ * The ticks of the schedule after the given one and before the
+ * minute, the last CATCHUP_MAX_RUNS of them.
+ */
+func (s *Schedule) missedBetween(checked, minute time.Time, env *ScheduleEnv) []time.Time {
+	ticks := make([]time.Time, 0)
+	at := checked
+	for i := 0; i < CATCHUP_MAX_TICKS; i++ {
+		tick, err := s.NextTick(at, env, false)
+		if err != nil || !tick.Before(minute) {
+			break
+		}
+		if len(ticks) == CATCHUP_MAX_RUNS {
+			ticks = ticks[1:]
+		}
+		ticks = append(ticks, tick)
+		at = tick
+	}
+	return ticks
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+func ValidateCatchUpPolicy(policy string) error {
+	switch policy {
+	case "", CATCHUP_NONE, CATCHUP_LATEST, CATCHUP_ALL:
+		return nil
+	}
+	return fmt.Errorf("catch-up policy must be %s, %s or %s, not %q", CATCHUP_NONE, CATCHUP_LATEST, CATCHUP_ALL, policy)
+}
+
+/**
+ * The runs missed since each schedule was last checked, oldest first.
+ * Schedules never checked before, interval ones (they are due anyway
+ * once the interval is up), those whose conditions don't hold now,
+ * wallpaper changes while the carousel was locked (as it is now and
+ * as the missed runs lock & unlock it) and, under the latest policy,
+ * runs a later one of the same or a conflicting schedule would
+ * override are left out.
+ */
+func MissedRuns(settings *Settings, env *ScheduleEnv, now time.Time) ([]MissedRun, error) {
+	store, err := NewStateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	minute := now.Truncate(time.Minute)
+	runs := make([]MissedRun, 0)
+	for idx := range settings.Schedules {
+		job := &settings.Schedules[idx]
+		policy := job.CatchUpPolicy(&settings.UserOptions)
+		record, ok := store.Schedules[job.Title]
+		if policy == CATCHUP_NONE || !ok || job.IsInterval() || job.Validate(env) != nil {
+			continue
+		}
+		if job.When != nil {
+			if hold, err := job.When.Hold(env.Probe); !hold || err != nil {
+				continue
+			}
+		}
+
+		checked := record.LastCheck
+		if oldest := minute.Add(-CATCHUP_MAX_AGE); checked.Before(oldest) {
+			checked = oldest
+		}
+		for _, tick := range job.missedBetween(checked, minute, env) {
+			runs = append(runs, MissedRun{idx, tick})
+		}
+	}
+	slices.SortStableFunc(runs, func(a, b MissedRun) int {
+		return a.At.Compare(b.At)
+	})
+
+	// in order, what the lock held back back then is held back now
+	locked := IsLocked(settings)
+	unlocked := make([]MissedRun, 0, len(runs))
+	for _, run := range runs {
+		switch job := &settings.Schedules[run.Job]; {
+		case job.Command == ActLockCarousel:
+			locked = true
+		case job.Command == ActUnlockCarousel:
+			locked = false
+		case locked && job.Command.ChangesWallpaper():
+			continue
+		}
+		unlocked = append(unlocked, run)
+	}
+
+	// newest first, keep what a later run doesn't override
+	kept := make([]MissedRun, 0, len(unlocked))
+	for i := len(unlocked) - 1; i >= 0; i-- {
+		run := unlocked[i]
+		job := &settings.Schedules[run.Job]
+		overridden := slices.ContainsFunc(kept, func(later MissedRun) bool {
+			return later.Job == run.Job || (later.At.After(run.At) && settings.Schedules[later.Job].ConflictsWith(job))
+		})
+		if !overridden || job.CatchUpPolicy(&settings.UserOptions) == CATCHUP_ALL {
+			kept = append(kept, run)
+		}
+	}
+	slices.Reverse(kept)
+	return kept, nil
+}
+
+/**
+ * Remember that the schedules were checked at the given time and which
+ * of them ran, for those that catch up and interval ones.
+ * @param checked ([]int) indices of the schedules checked
+ * @param ran ([]int) indices of those that ran (or caught up)
+ */
+func RecordScheduleRuns(settings *Settings, checked, ran []int, at time.Time) error {
+	store, err := NewStateStore()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, idx := range checked {
+		job := &settings.Schedules[idx]
+		if job.isRecorded(&settings.UserOptions) {
+			store.MarkSchedule(job.Title, at, slices.Contains(ran, idx))
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return store.Save()
+}
@@ -8,10 +8,18 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log"
 	"lordofscripts/carousel"
+	"lordofscripts/carousel/app"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/adhocore/gronx/pkg/tasker"
@@ -24,8 +32,25 @@ import (
 const (
 	DAEMON_VERBOSE          bool = false
 	DAEMON_CONCURRENT_TASKS bool = false
+	DAEMON_FOREVER               = 0 // minutes, run until stopped
+	EVERY_MINUTE                 = "* * * * *"
 )
 
+// what others leave to the angel while it runs, so as not to race it
+var controlActions = []carousel.Action{
+	carousel.ActDefaultWallpaper,
+	carousel.ActAnyWallpaper,
+	carousel.ActChosenFile,
+	carousel.ActChosenCarousel,
+	carousel.ActUndoWallpaper,
+	carousel.ActFavorite,
+	carousel.ActUnfavorite,
+	carousel.ActRateWallpaper,
+	carousel.ActBanWallpaper,
+	carousel.ActTagWallpaper,
+	carousel.ActUntagWallpaper,
+}
+
 /* ----------------------------------------------------------------
  *				P u b l i c		T y p e s
  *-----------------------------------------------------------------*/
@@ -34,11 +59,237 @@ const (
  *				P r i v a t e	T y p e s
  *-----------------------------------------------------------------*/
 
+/**
+ * The state of the daemon, shared by the minute task and whoever
+ * controls it meanwhile.
+ */
+type angel struct {
+	mu       sync.Mutex
+	settings *carousel.Settings
+	env      *carousel.ScheduleEnv
+	jobs     []int // indices of the valid schedules
+	paused   bool
+	logger   *log.Logger
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+func newAngel(settings *carousel.Settings, logger *log.Logger) *angel {
+	a := &angel{logger: logger}
+	a.load(settings)
+	return a
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Take on the settings: every schedule is checked each minute, not all
+ * of them are cron, and those due together are resolved by the
+ * conflict policy.
+ */
+func (a *angel) load(settings *carousel.Settings) {
+	env, err := carousel.NewScheduleEnv(settings)
+	if err != nil {
+		a.logger.Printf("calendars: %s", err)
+	}
+	jobs := make([]int, 0, len(settings.Schedules))
+	for jid, job := range settings.Schedules {
+		err := job.Validate(env)
+		if err == nil {
+			err = job.CheckWindow(time.Now(), env)
+		}
+		if err != nil {
+			a.logger.Printf("skipping Job #%d %s: %s", jid+1, job.Title, err)
+			continue
+		}
+		jobs = append(jobs, jid)
+	}
+	a.settings, a.env, a.jobs = settings, env, jobs
+}
+
+/**
+ * Whatever was missed while we were not around.
+ */
+func (a *angel) catchUp() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ran := make([]int, 0)
+	missed, err := carousel.MissedRuns(a.settings, a.env, time.Now())
+	if err != nil {
+		a.logger.Printf("catch-up error: %s", err)
+	}
+	for _, run := range missed {
+		job := a.settings.Schedules[run.Job]
+		a.logger.Printf("catching up on Job #%d %s missed at %s", run.Job+1, job.Title, run.At.Format("Jan 2 15:04"))
+		if err := carousel.ExecuteJob(job, a.settings); err != nil {
+			a.logger.Printf("Job #%d exec error: %s", run.Job+1, err)
+		} else {
+			ran = append(ran, run.Job)
+		}
+	}
+	if err := carousel.RecordScheduleRuns(a.settings, a.jobs, ran, time.Now()); err != nil {
+		a.logger.Printf("could not record schedule runs: %s", err)
+	}
+}
+
+/**
+ * Run the schedules due this minute. While paused they are checked
+ * but not run, so that they aren't caught up later either.
+ */
+func (a *angel) tick(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.env.Reload() // changes made meanwhile, manual ones too, reset intervals
+	due := make([]int, 0)
+	for _, jid := range a.jobs {
+		if a.paused {
+			break
+		}
+		if ok, err := a.settings.Schedules[jid].IsDue(now, a.env); err != nil {
+			a.logger.Printf("Job #%d due error: %s", jid+1, err)
+		} else if ok {
+			due = append(due, jid)
+		}
+	}
+
+	ran := make([]int, 0, len(due))
+	for _, jid := range carousel.ResolveConflicts(a.settings.Schedules, due, a.settings.UserOptions.Conflicts) {
+		job := a.settings.Schedules[jid]
+		a.logger.Printf("running Job #%d %s", jid+1, job.Title)
+		if err := carousel.ExecuteJob(job, a.settings); err != nil {
+			a.logger.Printf("Job #%d exec error: %s", jid+1, err)
+		} else {
+			ran = append(ran, jid)
+		}
+	}
+	if err := carousel.RecordScheduleRuns(a.settings, a.jobs, ran, now); err != nil {
+		a.logger.Printf("could not record schedule runs: %s", err)
+	}
+}
+
+/**
+ * Do as asked through the control socket or by a signal, and tell
+ * how things stand afterwards.
+ */
+func (a *angel) Control(request carousel.ControlRequest) carousel.ControlReply {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var reply carousel.ControlReply
+	if err := a.control(request); err != nil {
+		reply.Error = err.Error()
+	}
+	reply.Paused, reply.Locked = a.paused, carousel.IsLocked(a.settings)
+	if status, err := carousel.GetWallpaperStatus(); err == nil {
+		reply.Wallpaper = status
+	}
+	return reply
+}
+
+func (a *angel) control(request carousel.ControlRequest) error {
+	target := cmp.Or(request.Target, carousel.TargetDesktop)
+	if !target.IsValid() {
+		return fmt.Errorf("unknown target %q", request.Target)
+	}
+
+	switch request.Command {
+	case carousel.CONTROL_NEXT, carousel.CONTROL_PREVIOUS, carousel.CONTROL_CATEGORY:
+		if carousel.IsLocked(a.settings) {
+			return fmt.Errorf("carousel is locked")
+		}
+		action := map[string]carousel.Action{
+			carousel.CONTROL_NEXT:     carousel.ActNextWallpaper,
+			carousel.CONTROL_PREVIOUS: carousel.ActPreviousWallpaper,
+			carousel.CONTROL_CATEGORY: carousel.ActChosenCategory,
+		}[request.Command]
+		if action == carousel.ActChosenCategory && request.Argument == "" {
+			return fmt.Errorf("category needs the name of one")
+		}
+		if err := carousel.ExecuteOn(target, action, request.Argument, a.settings); err != nil {
+			return err
+		}
+		a.logger.Printf("%s on request", strings.TrimSpace(request.Command+" "+request.Argument))
+
+	case carousel.CONTROL_ACTION:
+		if !slices.Contains(controlActions, request.Action) {
+			return fmt.Errorf("%s can't be done by the angel", request.Action)
+		}
+		if carousel.IsLocked(a.settings) {
+			return fmt.Errorf("carousel is locked")
+		}
+		if err := carousel.ExecuteOn(target, request.Action, request.Argument, a.settings); err != nil {
+			return err
+		}
+		a.logger.Printf("%s on request", strings.TrimSpace(request.Action.String()+" "+request.Argument))
+
+	case carousel.CONTROL_PAUSE, carousel.CONTROL_RESUME, carousel.CONTROL_TOGGLE:
+		switch request.Command {
+		case carousel.CONTROL_PAUSE:
+			a.paused = true
+		case carousel.CONTROL_RESUME:
+			a.paused = false
+		default:
+			a.paused = !a.paused
+		}
+		a.logger.Printf("schedules paused: %t", a.paused)
+
+	case carousel.CONTROL_LOCK:
+		return carousel.LockCarousel(a.settings)
+
+	case carousel.CONTROL_UNLOCK:
+		return carousel.UnlockCarousel(a.settings)
+
+	case carousel.CONTROL_STATUS:
+
+	case carousel.CONTROL_RELOAD:
+		settings, err := getSettings(getConfigFilename())
+		if err != nil {
+			return fmt.Errorf("keeping the configuration: %w", err)
+		}
+		a.load(settings)
+		a.logger.Printf("reloaded %s, %d of %d schedules valid", getConfigFilename(), len(a.jobs), len(settings.Schedules))
+
+	default:
+		return fmt.Errorf("unknown command %q", request.Command)
+	}
+	return nil
+}
+
+/**
+ * Serve the controlling signals until the channel is closed.
+ */
+func (a *angel) listen(signals <-chan os.Signal) {
+	for sig := range signals {
+		reply := a.Control(carousel.ControlRequest{Command: controlSignals[sig]})
+		if reply.Error != "" {
+			a.logger.Printf("%s: %s", sig, reply.Error)
+		}
+	}
+}
+
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
  *-----------------------------------------------------------------*/
 
+/**
+ * Run the schedules for some minutes, or until stopped when
+ * DAEMON_FOREVER. SIGINT & SIGTERM stop it after the LastAction, other
+ * signals (see controlSignals) and the control socket control it
+ * meanwhile.
+ */
 func CarouselTasker(settings *carousel.Settings, runUntilMinutes int) {
+	control, err := carousel.NewControlServer()
+	if err != nil {
+		app.DieWithError(err, 9)
+	}
+	defer control.Close()
+
 	log.Println("Angel battering wings in wallpaper heaven...")
 	carousel.ExecuteCommand(settings.AngelOptions.FirstAction, settings)
 	log.Print("Executed Angel.FirstAction")
@@ -54,24 +305,36 @@ func CarouselTasker(settings *carousel.Settings, runUntilMinutes int) {
 	// run task without overlap, set concurrent flag to false:
 	concurrent := DAEMON_CONCURRENT_TASKS
 
-	for jid, job := range settings.Schedules {
-		taskr.Task(job.CronTab, func(ctx context.Context) (int, error) {
-			taskr.Log.Printf("running Job #%d %s", jid+1, job.Title)
+	heaven := newAngel(settings, taskr.Log)
+	heaven.catchUp()
+	go control.Serve(heaven)
 
-			err := carousel.Execute(job.Command, job.Argument, settings)
-			return 0, err
-		}, concurrent)
+	if len(controlSignals) != 0 {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, slices.Collect(maps.Keys(controlSignals))...)
+		defer close(signals)
+		defer signal.Stop(signals)
+		go heaven.listen(signals)
 	}
 
+	taskr.Task(EVERY_MINUTE, func(ctx context.Context) (int, error) {
+		heaven.tick(time.Now())
+		return 0, nil
+	}, concurrent)
+
 	// optionally if you want tasker to stop after 2 hour, pass the duration with Until():
 	//taskr.Until(2 * time.Hour)
-	taskr.Until(time.Duration(runUntilMinutes) * time.Minute)
+	if runUntilMinutes != DAEMON_FOREVER {
+		taskr.Until(time.Duration(runUntilMinutes) * time.Minute)
+	}
 
 	// finally run the tasker, it ticks sharply on every minute and runs all the tasks due on that time!
 	// it exits gracefully when ctrl+c is received making sure pending tasks are completed.
 	taskr.Run()
 
-	carousel.ExecuteCommand(settings.AngelOptions.LastAction, settings)
+	heaven.mu.Lock() // the settings may have been reloaded
+	carousel.ExecuteCommand(heaven.settings.AngelOptions.LastAction, heaven.settings)
+	heaven.mu.Unlock()
 	log.Print("Executed Angel.LastAction")
 
 	fmt.Println("Angels says goodbye...")
@@ -8,6 +8,7 @@
 package main
 
 import (
+	"cmp"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -16,11 +17,11 @@ import (
 	"lordofscripts/carousel/app"
 	"os"
 	"path"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
-
-	"github.com/adhocore/gronx"
 )
 
 /* ----------------------------------------------------------------
@@ -29,8 +30,14 @@ import (
 const (
 	CONFIG_GROUP  string = "coralys"
 	SETTINGS_FILE string = "goCarousel.json"
+
+	VERIFY_MAX_EVENTS   = 3 // calendar matches shown per schedule
+	VERIFY_MAX_OVERLAPS = 3 // times shown per pair of conflicting schedules
 )
 
+// -simulate FROM TO, either a date or a time of the day
+var SIMULATE_TIME_LAYOUTS = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02 15:04"}
+
 /* ----------------------------------------------------------------
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
@@ -175,34 +182,57 @@ func CreateDefault(location string) error {
 
 func CronTask(settings *carousel.Settings, tellNext bool) error {
 	if len(settings.Schedules) > 0 {
-		gron := gronx.New()
+		env, err := carousel.NewScheduleEnv(settings)
+		if err != nil {
+			log.Printf("calendars: %s", err)
+		}
+
+		// remember what we checked & ran for those catching up
+		now := time.Now()
+		checkedJobs, ranJobs := make([]int, 0), make([]int, 0)
+		defer func() {
+			if err := carousel.RecordScheduleRuns(settings, checkedJobs, ranJobs, now); err != nil {
+				log.Printf("could not record schedule runs: %s", err)
+			}
+		}()
 
-		exprs := make([]string, len(settings.Schedules))
-		for idx, sched := range settings.Schedules {
-			exprs[idx] = sched.CronTab
+		anyTaskDue := false
+		missed, err := carousel.MissedRuns(settings, env, now)
+		if err != nil {
+			log.Printf("catch-up error: %s", err)
+		}
+		for _, run := range missed {
+			job := settings.Schedules[run.Job]
+			log.Printf("catching up on job #%d '%s' missed at %s", run.Job+1, job.Title, run.At.Format("Jan 2 15:04"))
+			if err := carousel.ExecuteJob(job, settings); err != nil {
+				log.Printf("job #%d '%s' exec error: %s", run.Job+1, job.Title, err)
+			} else {
+				ranJobs = append(ranJobs, run.Job)
+				anyTaskDue = true
+			}
+		}
+		if len(ranJobs) != 0 {
+			env.Reload() // intervals count from the runs caught up
 		}
 
 		const TIMESTAMP_LAYOUT = "2006-01-02 15:04:05 -0700 MST"
 		var jobSlice carousel.JobInfoSlice = make(carousel.JobInfoSlice, 0)
-		anyTaskDue := false
+		dueJobs := make([]int, 0)
 		for idx, job := range settings.Schedules {
-			if gron.IsValid(job.CronTab) {
-				due, err := gron.IsDue(job.CronTab, time.Now())
+			if err := job.Validate(env); err != nil {
+				log.Printf("skipping job #%d '%s': %s", idx+1, job.Title, err)
+			} else {
+				checkedJobs = append(checkedJobs, idx)
+				due, err := job.IsDue(now, env)
 				if err != nil {
 					log.Printf("job #%d '%s' due error: %s", idx+1, job.Title, err)
 				} else if due {
-					if err := carousel.Execute(job.Command, job.Argument, settings); err != nil {
-						log.Printf("job #%d '%s' exec error: %s", idx+1, job.Title, err)
-						return err
-					} else {
-						log.Printf("Success running %s", job.Title)
-						anyTaskDue = true
-					}
+					dueJobs = append(dueJobs, idx)
 				}
 
 				if !due && tellNext {
 					allowCurrent := true // include current time
-					nextTime, err := gronx.NextTick(job.CronTab, allowCurrent)
+					nextTime, err := job.NextTick(now, env, allowCurrent)
 					if err == nil {
 						jobSlice = append(jobSlice, carousel.JobInfo{
 							Id:        uint(idx + 1),
@@ -214,6 +244,26 @@ func CronTask(settings *carousel.Settings, tellNext bool) error {
 			}
 		}
 
+		runJobs := carousel.ResolveConflicts(settings.Schedules, dueJobs, settings.UserOptions.Conflicts)
+		for _, idx := range dueJobs {
+			job := settings.Schedules[idx]
+			if !slices.Contains(runJobs, idx) {
+				log.Printf("job #%d '%s' yields to a conflicting job (%s)", idx+1, job.Title, settings.UserOptions.Conflicts)
+				continue
+			}
+
+			if err := carousel.ExecuteJob(job, settings); carousel.IsWarning(err) {
+				log.Printf("job #%d '%s': %s", idx+1, job.Title, err)
+			} else if err != nil {
+				log.Printf("job #%d '%s' exec error: %s", idx+1, job.Title, err)
+				return err
+			} else {
+				log.Printf("Success running %s", job.Title)
+				ranJobs = append(ranJobs, idx)
+				anyTaskDue = true
+			}
+		}
+
 		if tellNext {
 			sort.Sort(carousel.JobInfoSlice(jobSlice))
 			fmt.Println("\tTasks Next Due...")
@@ -232,6 +282,97 @@ func CronTask(settings *carousel.Settings, tellNext bool) error {
 	return nil
 }
 
+/**
+ * Dry run the schedules from FROM to TO, a date alone stands for the
+ * whole day.
+ */
+func Simulate(settings *carousel.Settings, fromText, untilText string, seed uint64, format, output string) error {
+	from, _, err := parseSimulateTime(fromText)
+	if err != nil {
+		return err
+	}
+	until, untilDate, err := parseSimulateTime(untilText)
+	if err != nil {
+		return err
+	}
+	if untilDate {
+		until = until.AddDate(0, 0, 1)
+	}
+	if !until.After(from) {
+		return fmt.Errorf("-simulate needs TO after FROM")
+	}
+
+	env, err := carousel.NewScheduleEnv(settings)
+	if err != nil {
+		log.Printf("calendars: %s", err)
+	}
+	for idx, job := range settings.Schedules {
+		jerr := job.Validate(env)
+		if jerr == nil {
+			jerr = job.CheckWindow(from, env)
+		}
+		if jerr != nil {
+			log.Printf("skipping job #%d '%s': %s", idx+1, job.Title, jerr)
+		}
+	}
+
+	steps, serr := carousel.NewSimulator(settings, env, seed).Run(from, until)
+	out := os.Stdout
+	if output != "" {
+		if out, err = os.Create(output); err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+	if err = carousel.WriteSimulation(out, steps, format); err != nil {
+		return err
+	}
+	return serr
+}
+
+/**
+ * @returns (bool) whether it is a date alone
+ */
+func parseSimulateTime(text string) (time.Time, bool, error) {
+	for idx, layout := range SIMULATE_TIME_LAYOUTS {
+		if at, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return at, idx == 0, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("-simulate FROM TO must be YYYY-MM-DD or YYYY-MM-DDTHH:MM, not %q", text)
+}
+
+/**
+ * Have the running angel daemon do as asked. It exits but for the
+ * status, which goes on locally.
+ */
+func ForwardToAngel(request carousel.ControlRequest) {
+	changes := []string{carousel.CONTROL_NEXT, carousel.CONTROL_PREVIOUS, carousel.CONTROL_CATEGORY, carousel.CONTROL_ACTION}
+	reply, err := carousel.SendControl(request)
+	if err != nil {
+		app.DieWithError(err, 9)
+	}
+	if err = reply.Err(); err != nil {
+		if reply.Locked && slices.Contains(changes, request.Command) {
+			os.Exit(124) // as when acting by ourselves
+		}
+		app.DieWithError(err, 6)
+	}
+
+	switch {
+	case request.Command == carousel.CONTROL_STATUS:
+		fmt.Println("Angel daemon is running, schedules paused:", reply.Paused)
+		return
+	case slices.Contains(changes, request.Command) && reply.Wallpaper != nil:
+		fmt.Println("Wallpaper:", reply.Wallpaper.Path)
+	case request.Command == carousel.CONTROL_PAUSE || request.Command == carousel.CONTROL_RESUME:
+		fmt.Println("Schedules paused:", reply.Paused)
+	case request.Command == carousel.CONTROL_RELOAD:
+		fmt.Println("Configuration reloaded")
+	}
+	os.Exit(0)
+}
+
 func Version() {
 	carousel.Copyright(carousel.CO1, true)
 	carousel.BuyMeCoffee("lostinwriting")
@@ -254,9 +395,18 @@ func Help() {
 	fmt.Println(NAME, "-C|-category CATEGORY")
 	fmt.Println(NAME, "-G|-carousel NAME")
 	fmt.Println(NAME, "-F /path/to/wallpaper.jpg")
+	fmt.Println(NAME, "[-target desktop|lockscreen|both] (with the above)")
+	fmt.Println("\t\t\t(History)")
+	fmt.Println(NAME, "-previous|-next|-undo")
+	fmt.Println(NAME, "-favorite|-unfavorite|-ban|-rate 1..5")
+	fmt.Println(NAME, "-tag|-untag TAG1,TAG2")
+	fmt.Println(NAME, "-reindex|-duplicates")
+	fmt.Println(NAME, "-C|-G NAME -export FILE.xml [-duration 30m]")
 	fmt.Println("\t\t\t(Scheduling)")
 	fmt.Println(NAME, "-task [-next]")
-	fmt.Println(NAME, "-daemon MINUTES")
+	fmt.Println(NAME, "-daemon MINUTES (0 until stopped)")
+	fmt.Println(NAME, "-pause|-resume|-reload (of the running daemon)")
+	fmt.Println(NAME, "-simulate FROM TO [-seed N] [-format text|json|csv] [-output FILE]")
 	//flag.PrintDefaults()
 
 	carousel.BuyMeCoffee("lostinwriting")
@@ -272,8 +422,17 @@ func main() {
 	// ============= CLI FLAGS ===============
 	var actInit, actHelp, actVersion, actAnyGlobal, actLock, actUnlock, actStatus, actDefault, actVerify, actWhoAmI bool
 	var actTask, optNextTime bool
+	var actPause, actResume, actReload bool
+	var actPrevious, actUndo bool
+	var actFavorite, actUnfavorite, actBan, actReindex, actDuplicates bool
+	var optRating int
 	var actDaemon int
-	var group, category, filename string
+	var group, category, filename, target string
+	var tags, untags string
+	var exportFile string
+	var optDuration time.Duration
+	var simulateFrom, simFormat, simOutput string
+	var optSeed uint64
 
 	flag.BoolVar(&actHelp, "help", false, "Cry for help!")
 	flag.BoolVar(&actVersion, "version", false, "Show version")
@@ -285,15 +444,39 @@ func main() {
 	flag.BoolVar(&actDefault, "default", false, "Set default wallpaper")
 	flag.BoolVar(&actAnyGlobal, "any", false, "Select from Default wallpapers")
 	flag.BoolVar(&actTask, "task", false, "Run any Wallpaper task that is due")
-	flag.BoolVar(&optNextTime, "next", false, "Show when task is next due (only with -task)")
-	flag.IntVar(&actDaemon, "daemon", -1, "Run as a dumb daemon for N minutes")
+	flag.BoolVar(&optNextTime, "next", false, "Show when task is next due (with -task) else pick a fresh wallpaper from the same source")
+	flag.BoolVar(&actPrevious, "previous", false, "Go back to the previous wallpaper in history")
+	flag.BoolVar(&actUndo, "undo", false, "Forget the last wallpaper change and restore the one before")
+	flag.BoolVar(&actFavorite, "favorite", false, "Add the current wallpaper to the Favorites")
+	flag.BoolVar(&actUnfavorite, "unfavorite", false, "Remove the current wallpaper from the Favorites")
+	flag.IntVar(&optRating, "rate", -1, "Rate the current wallpaper 1..5 (0 to unrate)")
+	flag.BoolVar(&actBan, "ban", false, "Never show the current wallpaper again")
+	flag.BoolVar(&actReindex, "reindex", false, "Rebuild the wallpaper catalog")
+	flag.BoolVar(&actDuplicates, "duplicates", false, "Report groups of (near-)duplicate wallpapers")
+	flag.StringVar(&tags, "tag", "", "Tag the current wallpaper (comma-separated)")
+	flag.StringVar(&untags, "untag", "", "Remove tags from the current wallpaper (comma-separated)")
+	flag.StringVar(&exportFile, "export", "", "Save the category (-C) or carousel (-G) as a GNOME XML slideshow")
+	flag.DurationVar(&optDuration, "duration", carousel.SLIDESHOW_DURATION, "How long each picture is shown (with -export)")
+	flag.IntVar(&actDaemon, "daemon", -1, "Run as a dumb daemon for N minutes (0 until stopped)")
+	flag.BoolVar(&actPause, "pause", false, "Pause the schedules of the running daemon")
+	flag.BoolVar(&actResume, "resume", false, "Resume the schedules of the running daemon")
+	flag.BoolVar(&actReload, "reload", false, "Have the running daemon read the configuration again")
+	flag.StringVar(&simulateFrom, "simulate", "", "Dry run the schedules from FROM to TO (YYYY-MM-DD[THH:MM])")
+	flag.Uint64Var(&optSeed, "seed", 0, "Seed of the random picks (with -simulate)")
+	flag.StringVar(&simFormat, "format", carousel.SIM_FORMAT_TEXT, "Output text, json or csv (with -simulate)")
+	flag.StringVar(&simOutput, "output", "", "Write to this file instead of the console (with -simulate)")
 	flag.BoolVar(&actWhoAmI, "ident", false, "Identify and exit")
 	flag.StringVar(&category, "C", "", "Select from this category")
 	flag.StringVar(&category, "category", "", "Select from this category")
 	flag.StringVar(&filename, "F", "", "Select this wallpaper")
 	flag.StringVar(&group, "G", "", "Select this caroussel group")
 	flag.StringVar(&group, "carousel", "", "Select this caroussel group")
+	flag.StringVar(&target, "target", string(carousel.TargetDesktop), "Apply to desktop, lockscreen or both")
 	flag.Parse()
+	simulateUntil := flag.Arg(0)
+	if simulateFrom != "" && flag.NArg() > 1 { // flags after -simulate FROM TO
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
 
 	// ============= CLI PROCESS ===============
 	if actVersion {
@@ -350,6 +533,109 @@ func main() {
 		app.DieWithError(err, 2)
 	}
 
+	// (@) Wallpaper actions, by a running angel or else by ourselves
+	var action carousel.Action = carousel.ActNone
+	var argument string = ""
+
+	if actAnyGlobal {
+		action = carousel.ActAnyWallpaper
+	}
+	if group != "" {
+		action = carousel.ActChosenCarousel
+		argument = group
+	}
+	if category != "" {
+		action = carousel.ActChosenCategory
+		argument = category
+	}
+	if filename != "" {
+		action = carousel.ActChosenFile
+		argument = filename
+	}
+	if actDefault {
+		action = carousel.ActDefaultWallpaper
+		argument = settings.DefaultWallpaper
+	}
+	if actPrevious {
+		action = carousel.ActPreviousWallpaper
+	}
+	if optNextTime && !actTask {
+		action = carousel.ActNextWallpaper
+	}
+	if actUndo {
+		action = carousel.ActUndoWallpaper
+	}
+	if actFavorite {
+		action = carousel.ActFavorite
+	}
+	if actUnfavorite {
+		action = carousel.ActUnfavorite
+	}
+	if optRating > -1 {
+		action = carousel.ActRateWallpaper
+		argument = strconv.Itoa(optRating)
+	}
+	if actBan {
+		action = carousel.ActBanWallpaper
+	}
+	if tags != "" {
+		action = carousel.ActTagWallpaper
+		argument = tags
+	}
+	if untags != "" {
+		action = carousel.ActUntagWallpaper
+		argument = untags
+	}
+	if actReindex {
+		action = carousel.ActReindex
+	}
+	if actDuplicates {
+		action = carousel.ActFindDuplicates
+	}
+	if actWhoAmI {
+		action = carousel.ActIdentify
+	}
+
+	// a running angel does what it can, so that we don't race it
+	request := carousel.ControlRequest{Target: carousel.WallpaperTarget(target)}
+	switch {
+	case actPause:
+		request.Command = carousel.CONTROL_PAUSE
+	case actResume:
+		request.Command = carousel.CONTROL_RESUME
+	case actReload:
+		request.Command = carousel.CONTROL_RELOAD
+	case actLock:
+		request.Command = carousel.CONTROL_LOCK
+	case actUnlock:
+		request.Command = carousel.CONTROL_UNLOCK
+	case actStatus:
+		request.Command = carousel.CONTROL_STATUS
+	case category != "" && exportFile == "":
+		request.Command, request.Argument = carousel.CONTROL_CATEGORY, category
+	case optNextTime && !actTask:
+		request.Command = carousel.CONTROL_NEXT
+	case actPrevious:
+		request.Command = carousel.CONTROL_PREVIOUS
+	case exportFile != "" || simulateFrom != "": // nothing changes
+	case action == carousel.ActReindex || action == carousel.ActFindDuplicates:
+		if carousel.IsAngelRunning() {
+			app.Die("the angel daemon is running and uses the catalog too, stop it first", 9)
+		}
+	case action != carousel.ActNone && action != carousel.ActIdentify:
+		request.Command, request.Action, request.Argument = carousel.CONTROL_ACTION, action, argument
+	}
+	angelRunning := carousel.IsAngelRunning()
+	if angelRunning && request.Command != "" {
+		ForwardToAngel(request)
+	} else if actPause || actResume || actReload {
+		app.Die("no angel daemon running (see -daemon)", 9)
+	}
+	if angelRunning && actTask {
+		log.Print("the angel daemon runs the schedules")
+		os.Exit(0)
+	}
+
 	if actLock {
 		if err = carousel.LockCarousel(settings); err != nil {
 			app.DieWithError(err, 3)
@@ -365,13 +651,18 @@ func main() {
 	}
 
 	if actStatus {
-		if carousel.IsLocked(settings) {
+		locked := carousel.IsLocked(settings)
+		if locked {
 			fmt.Println("Carousel is Locked")
-			os.Exit(125)
 		} else {
 			fmt.Println("Carousel is NOT locked")
-			os.Exit(0)
 		}
+		carousel.PrintWallpaperStatus()
+		carousel.PrintCatalogStats()
+		if locked {
+			os.Exit(125)
+		}
+		os.Exit(0)
 	}
 
 	if actVerify {
@@ -382,24 +673,145 @@ func main() {
 		fmt.Printf("Configuration: %s\n", getConfigFilename())
 		fmt.Println("Verifying Cron Jobs...")
 		var cumulative bool = true
+		env, err := carousel.NewScheduleEnv(settings)
+		if err != nil {
+			cumulative = false
+			fmt.Println("\tcalendars:", err)
+		} else if env.Calendar != nil {
+			fmt.Printf("\tcalendars: %d events\n", len(env.Calendar.Events))
+			for _, reason := range env.Calendar.Skipped {
+				fmt.Println("\t\tskipped", reason)
+			}
+		}
 		for idx, crontab := range settings.Schedules {
-			ok := gronx.IsValid(crontab.CronTab)
-			cumulative = cumulative && ok
-			fmt.Printf("\t#%2d %t %s\n", idx+1, ok, crontab.Title)
+			serr := crontab.Validate(env)
+			if serr == nil {
+				serr = crontab.CheckWindow(time.Now(), env)
+			}
+			cumulative = cumulative && serr == nil
+			fmt.Printf("\t#%2d %t %s\n", idx+1, serr == nil, crontab.Title)
+			if serr != nil {
+				fmt.Println("\t\t", serr)
+			} else if crontab.Calendar != nil {
+				now := time.Now()
+				matches := env.Calendar.Matching(crontab.Calendar, now, now.Add(carousel.SEARCH_HORIZON))
+				fmt.Printf("\t\t%d matching events within a year\n", len(matches))
+				for _, occurrence := range matches[:min(len(matches), VERIFY_MAX_EVENTS)] {
+					fmt.Println("\t\t", occurrence)
+				}
+			}
+			if serr == nil && crontab.IsInterval() {
+				if next, err := crontab.NextTick(time.Now(), env, true); err == nil {
+					fmt.Printf("\t\tevery %s, next due %s\n", crontab.Every, next.Format("2006-01-02 15:04 MST"))
+				}
+			}
+			if serr == nil && crontab.When != nil {
+				hold, err := crontab.When.Hold(env.Probe)
+				fmt.Printf("\t\tconditions hold now: %t\n", hold)
+				if err != nil {
+					fmt.Println("\t\t", err)
+				}
+			}
 		}
 		if !cumulative {
 			app.Die("Some Cron entries are invalid", 5)
 		}
+		policy := settings.UserOptions.Conflicts
+		if err = carousel.ValidateConflictPolicy(policy); err != nil {
+			app.DieWithError(err, 5)
+		}
+		if err = carousel.ValidateCatchUpPolicy(settings.UserOptions.CatchUp); err != nil {
+			app.DieWithError(err, 5)
+		}
+		fmt.Printf("Verifying Schedule Conflicts (%s)...\n", cmp.Or(policy, carousel.CONFLICT_ALL))
+		for i := range settings.Schedules {
+			for j := i + 1; j < len(settings.Schedules); j++ {
+				first, second := &settings.Schedules[i], &settings.Schedules[j]
+				if !first.ConflictsWith(second) {
+					continue
+				}
+				overlaps := first.OverlapsWith(second, time.Now(), env, VERIFY_MAX_OVERLAPS)
+				if len(overlaps) == 0 {
+					continue
+				}
+
+				fmt.Printf("\t#%2d %s & #%2d %s overlap\n", i+1, first.Title, j+1, second.Title)
+				for _, at := range overlaps {
+					fmt.Println("\t\t", at.Format("2006-01-02 15:04 MST"))
+				}
+				if run := carousel.ResolveConflicts(settings.Schedules, []int{i, j}, policy); len(run) == 1 {
+					fmt.Printf("\t\t#%d wins\n", run[0]+1)
+				}
+			}
+		}
+		if where := settings.UserOptions.Location; where != nil {
+			if err = where.Validate(); err != nil {
+				app.DieWithError(err, 5)
+			}
+		}
+		fmt.Println("Verifying Smart, Feed, Slideshow & Dynamic Categories...")
+		for name, category := range settings.Categories {
+			var qerr error
+			if category.IsSmart() {
+				qerr = category.Query.Validate()
+			} else if category.IsFeed() {
+				qerr = category.Feed.Validate()
+			} else if category.IsSlideshow() {
+				_, qerr = carousel.NewSlideshowFromFile(category.Slideshow)
+			} else if category.IsDynamic() {
+				var dynamic *carousel.DynamicWallpaper
+				if dynamic, qerr = carousel.NewDynamicWallpaper(category.Dynamic); qerr == nil {
+					qerr = dynamic.Validate(settings.UserOptions.Location)
+				}
+			} else {
+				continue
+			}
+			fmt.Printf("\t%s %t\n", name, qerr == nil)
+			if qerr != nil {
+				cumulative = false
+				fmt.Println("\t\t", qerr)
+			}
+		}
+		if !cumulative {
+			app.Die("Some smart, feed, slideshow or dynamic categories are invalid", 5)
+		}
+		if !carousel.IsHashAlgorithm(settings.UserOptions.Duplicates.Algorithm) {
+			app.Die("Unknown duplicates algorithm "+settings.UserOptions.Duplicates.Algorithm, 5)
+		}
 		os.Exit(0)
 	}
 
-	if actDaemon > -1 {
-		CarouselTasker(settings, actDaemon)
+	if exportFile != "" {
+		source := category
+		if source == "" {
+			source = group
+		}
+		if source == "" || optDuration <= 0 {
+			app.Die("-export needs a category (-C) or carousel (-G) and a positive -duration", 7)
+		}
+
+		wm := carousel.NewWallpaperMgr(settings)
+		if err = wm.Init(); err != nil {
+			app.DieWithError(err, 6)
+		}
+		show, err := wm.ExportSlideshow(source, exportFile, optDuration)
+		if err != nil {
+			app.DieWithError(err, 6)
+		}
+		fmt.Printf("%d pictures, %s each, saved to %s\n", len(show.Files()), optDuration, exportFile)
 		os.Exit(0)
 	}
 
-	if carousel.IsLocked(settings) {
-		os.Exit(124)
+	if simulateFrom != "" {
+		if err = Simulate(settings, simulateFrom, simulateUntil, optSeed, simFormat, simOutput); err != nil {
+			app.DieWithError(err, 8)
+		}
+		os.Exit(0)
+	}
+
+	if actDaemon > -1 {
+		CarouselTasker(settings, actDaemon)
+		os.Exit(0)
 	}
 
 	if actTask {
@@ -410,34 +822,16 @@ func main() {
 		}
 	}
 
-	// (@) Execute Wallpaper actions
-	var action carousel.Action = carousel.ActNone
-	var argument string = ""
-
-	if actAnyGlobal {
-		action = carousel.ActAnyWallpaper
-	}
-	if group != "" {
-		action = carousel.ActChosenCarousel
-		argument = group
-	}
-	if category != "" {
-		action = carousel.ActChosenCategory
-		argument = category
-	}
-	if filename != "" {
-		action = carousel.ActChosenFile
-		argument = filename
-	}
-	if actDefault {
-		action = carousel.ActDefaultWallpaper
-		argument = settings.DefaultWallpaper
+	if carousel.IsLocked(settings) { // the schedules may lock & unlock it
+		os.Exit(124)
 	}
-	if actWhoAmI {
-		action = carousel.ActIdentify
+
+	wallTarget := carousel.WallpaperTarget(target)
+	if !wallTarget.IsValid() {
+		app.Die("target must be one of desktop, lockscreen, both", 7)
 	}
 
-	err = carousel.Execute(action, argument, settings)
+	err = carousel.ExecuteOn(wallTarget, action, argument, settings)
 	log.Printf("exec %s %s returns %v", action, argument, err)
 	if err != nil {
 		app.DieWithError(err, 6)
@@ -0,0 +1,26 @@
+//go:build unix
+
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							   goCarousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Signals that control a running angel.
+ *-----------------------------------------------------------------*/
+package main
+
+import (
+	"lordofscripts/carousel"
+	"os"
+	"syscall"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+var controlSignals = map[os.Signal]string{
+	syscall.SIGHUP:  carousel.CONTROL_RELOAD,
+	syscall.SIGUSR1: carousel.CONTROL_NEXT,
+	syscall.SIGUSR2: carousel.CONTROL_TOGGLE,
+}
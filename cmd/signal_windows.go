@@ -0,0 +1,20 @@
+//go:build windows
+
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							   goCarousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Windows has no signals to control a running angel with.
+ *-----------------------------------------------------------------*/
+package main
+
+import (
+	"os"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+var controlSignals = map[os.Signal]string{}
@@ -0,0 +1,204 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Schedule conditions on the state of the system: power source,
+ * hostname, connected monitors, files & environment variables.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	POWER_AC      = "ac"
+	POWER_BATTERY = "battery"
+
+	CONDITION_NOT = "!"
+)
+
+/* ----------------------------------------------------------------
+ *				I n t e r f a c e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Where schedule conditions get the state of the system from. Tests
+ * and simulations may provide their own.
+ */
+type ISystemProbe interface {
+	/**
+	 * @returns (bool) true when running on battery, false on AC or
+	 * when there is no battery at all
+	 */
+	OnBattery() (bool, error)
+
+	Hostname() (string, error)
+
+	/**
+	 * @returns (int) number of connected monitors
+	 */
+	Monitors() (int, error)
+
+	FileExists(string) bool
+
+	LookupEnv(string) (string, bool)
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The probe of the machine we run on.
+ */
+type SystemProbe struct{}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (p SystemProbe) Hostname() (string, error) {
+	return os.Hostname()
+}
+
+func (p SystemProbe) FileExists(filename string) bool {
+	_, err := os.Stat(os.ExpandEnv(filename))
+	return err == nil
+}
+
+func (p SystemProbe) LookupEnv(name string) (string, bool) {
+	return os.LookupEnv(name)
+}
+
+/**
+ * Check the conditions are well formed.
+ */
+func (c *ScheduleConditions) Validate() error {
+	if c.Power != "" && c.Power != POWER_AC && c.Power != POWER_BATTERY {
+		return fmt.Errorf("power must be %s or %s, not %q", POWER_AC, POWER_BATTERY, c.Power)
+	}
+	if _, err := path.Match(strings.TrimPrefix(c.Hostname, CONDITION_NOT), ""); err != nil {
+		return fmt.Errorf("bad hostname pattern %q", c.Hostname)
+	}
+	if c.Monitors != "" {
+		if _, _, err := parseCount(c.Monitors); err != nil {
+			return err
+		}
+	}
+	if name, _, _ := strings.Cut(strings.TrimPrefix(c.Env, CONDITION_NOT), "="); c.Env != "" && name == "" {
+		return fmt.Errorf("env condition needs a variable name, not %q", c.Env)
+	}
+	return nil
+}
+
+/**
+ * Whether all the given conditions hold. The probe is only asked
+ * about those given.
+ */
+func (c *ScheduleConditions) Hold(probe ISystemProbe) (bool, error) {
+	if c.Power != "" {
+		battery, err := probe.OnBattery()
+		if err != nil {
+			return false, err
+		}
+		if battery != (c.Power == POWER_BATTERY) {
+			return false, nil
+		}
+	}
+
+	if c.Hostname != "" {
+		hostname, err := probe.Hostname()
+		if err != nil {
+			return false, err
+		}
+		pattern, negated := negation(c.Hostname)
+		matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(hostname))
+		if err != nil || matched == negated {
+			return false, err
+		}
+	}
+
+	if c.Monitors != "" {
+		compare, count, err := parseCount(c.Monitors)
+		if err != nil {
+			return false, err
+		}
+		connected, err := probe.Monitors()
+		if err != nil || !compare(connected, count) {
+			return false, err
+		}
+	}
+
+	if c.File != "" {
+		filename, negated := negation(c.File)
+		if probe.FileExists(filename) == negated {
+			return false, nil
+		}
+	}
+
+	if c.Env != "" {
+		spec, negated := negation(c.Env)
+		name, want, exact := strings.Cut(spec, "=")
+		value, ok := probe.LookupEnv(name)
+		matched := ok && ((exact && value == want) || (!exact && value != ""))
+		if matched == negated {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Strip the leading ! of a condition.
+ * @returns (bool) whether it was negated
+ */
+func negation(condition string) (string, bool) {
+	if rest, ok := strings.CutPrefix(condition, CONDITION_NOT); ok {
+		return rest, true
+	}
+	return condition, false
+}
+
+/**
+ * Parse a count with an optional comparison: 2, >=2, <2, !=1
+ */
+func parseCount(spec string) (func(int, int) bool, int, error) {
+	comparisons := []struct {
+		op      string
+		compare func(int, int) bool
+	}{
+		{">=", func(a, b int) bool { return a >= b }},
+		{"<=", func(a, b int) bool { return a <= b }},
+		{"!=", func(a, b int) bool { return a != b }},
+		{">", func(a, b int) bool { return a > b }},
+		{"<", func(a, b int) bool { return a < b }},
+		{"=", func(a, b int) bool { return a == b }},
+		{"", func(a, b int) bool { return a == b }},
+	}
+
+	spec = strings.TrimSpace(spec)
+	for _, c := range comparisons {
+		if rest, ok := strings.CutPrefix(spec, c.op); ok {
+			count, err := strconv.Atoi(strings.TrimSpace(rest))
+			if err != nil || count < 0 {
+				return nil, 0, fmt.Errorf("monitors must be a count like 2 or >=2, not %q", spec)
+			}
+			return c.compare, count, nil
+		}
+	}
+	return nil, 0, nil // unreachable, "" always matches
+}
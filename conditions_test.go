@@ -0,0 +1,158 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Schedule conditions against a made-up system.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type fakeProbe struct {
+	battery  bool
+	hostname string
+	monitors int
+	files    []string
+	env      map[string]string
+	err      error // of the probes that can fail
+}
+
+func (p fakeProbe) OnBattery() (bool, error) {
+	return p.battery, p.err
+}
+
+func (p fakeProbe) Hostname() (string, error) {
+	return p.hostname, p.err
+}
+
+func (p fakeProbe) Monitors() (int, error) {
+	return p.monitors, p.err
+}
+
+func (p fakeProbe) FileExists(filename string) bool {
+	return slices.Contains(p.files, filename)
+}
+
+func (p fakeProbe) LookupEnv(name string) (string, bool) {
+	value, ok := p.env[name]
+	return value, ok
+}
+
+/* ----------------------------------------------------------------
+ *					T e s t s
+ *-----------------------------------------------------------------*/
+
+func TestConditionsHold(t *testing.T) {
+	probe := fakeProbe{
+		battery:  true,
+		hostname: "Work-Laptop",
+		monitors: 2,
+		files:    []string{"/tmp/presenting"},
+		env:      map[string]string{"DESK": "office", "EMPTY": ""},
+	}
+
+	tests := []struct {
+		name       string
+		conditions ScheduleConditions
+		hold       bool
+	}{
+		{"none", ScheduleConditions{}, true},
+		{"battery", ScheduleConditions{Power: POWER_BATTERY}, true},
+		{"ac", ScheduleConditions{Power: POWER_AC}, false},
+		{"hostname", ScheduleConditions{Hostname: "work-laptop"}, true},
+		{"hostname glob", ScheduleConditions{Hostname: "work-*"}, true},
+		{"hostname glob other", ScheduleConditions{Hostname: "home-*"}, false},
+		{"hostname negated", ScheduleConditions{Hostname: "!work-*"}, false},
+		{"hostname negated other", ScheduleConditions{Hostname: "!home-*"}, true},
+		{"hostname single char", ScheduleConditions{Hostname: "work-lapto?"}, true},
+		{"monitors", ScheduleConditions{Monitors: "2"}, true},
+		{"monitors equal", ScheduleConditions{Monitors: "=1"}, false},
+		{"monitors at least", ScheduleConditions{Monitors: ">=2"}, true},
+		{"monitors more", ScheduleConditions{Monitors: ">2"}, false},
+		{"monitors at most", ScheduleConditions{Monitors: "<= 2"}, true},
+		{"monitors less", ScheduleConditions{Monitors: "<2"}, false},
+		{"monitors not", ScheduleConditions{Monitors: "!=1"}, true},
+		{"file", ScheduleConditions{File: "/tmp/presenting"}, true},
+		{"file missing", ScheduleConditions{File: "/tmp/other"}, false},
+		{"file negated", ScheduleConditions{File: "!/tmp/presenting"}, false},
+		{"file negated missing", ScheduleConditions{File: "!/tmp/other"}, true},
+		{"env set", ScheduleConditions{Env: "DESK"}, true},
+		{"env empty", ScheduleConditions{Env: "EMPTY"}, false},
+		{"env unset", ScheduleConditions{Env: "NOPE"}, false},
+		{"env value", ScheduleConditions{Env: "DESK=office"}, true},
+		{"env other value", ScheduleConditions{Env: "DESK=home"}, false},
+		{"env empty value", ScheduleConditions{Env: "EMPTY="}, true},
+		{"env negated", ScheduleConditions{Env: "!DESK=home"}, true},
+		{"env negated unset", ScheduleConditions{Env: "!NOPE"}, true},
+		{"all", ScheduleConditions{Power: POWER_BATTERY, Hostname: "work-*", Monitors: ">=2", File: "/tmp/presenting", Env: "DESK=office"}, true},
+		{"all but one", ScheduleConditions{Power: POWER_BATTERY, Hostname: "work-*", Monitors: ">=3", File: "/tmp/presenting", Env: "DESK=office"}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.conditions.Validate(); err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+			hold, err := test.conditions.Hold(probe)
+			if err != nil {
+				t.Fatalf("Hold() error = %v", err)
+			}
+			if hold != test.hold {
+				t.Errorf("Hold() = %t, want %t", hold, test.hold)
+			}
+		})
+	}
+}
+
+func TestConditionsProbeError(t *testing.T) {
+	failure := errors.New("no sysfs")
+	probe := fakeProbe{err: failure}
+
+	for _, conditions := range []ScheduleConditions{
+		{Power: POWER_AC},
+		{Hostname: "work-*"},
+		{Monitors: "1"},
+	} {
+		hold, err := conditions.Hold(probe)
+		if hold || !errors.Is(err, failure) {
+			t.Errorf("Hold(%+v) = %t, %v; want false, %v", conditions, hold, err, failure)
+		}
+	}
+
+	// only the conditions given are probed
+	if hold, err := (&ScheduleConditions{File: "!/tmp/x"}).Hold(probe); !hold || err != nil {
+		t.Errorf("Hold() = %t, %v; want true, nil", hold, err)
+	}
+}
+
+func TestConditionsValidate(t *testing.T) {
+	tests := []struct {
+		conditions ScheduleConditions
+		valid      bool
+	}{
+		{ScheduleConditions{Power: "solar"}, false},
+		{ScheduleConditions{Hostname: "work-["}, false},
+		{ScheduleConditions{Hostname: "!work-*"}, true},
+		{ScheduleConditions{Monitors: "two"}, false},
+		{ScheduleConditions{Monitors: ">=-1"}, false},
+		{ScheduleConditions{Monitors: " >= 2 "}, true},
+		{ScheduleConditions{Env: "=value"}, false},
+		{ScheduleConditions{Env: "!"}, false},
+		{ScheduleConditions{Env: "NAME=value"}, true},
+	}
+
+	for _, test := range tests {
+		if err := test.conditions.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v) = %v, want valid %t", test.conditions, err, test.valid)
+		}
+	}
+}
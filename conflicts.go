@@ -0,0 +1,119 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Schedules due in the same minute that would change the same
+ * wallpaper, and which of them get to run.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CONFLICT_ALL     = "all"     // run them all in order, the last one shows
+	CONFLICT_HIGHEST = "highest" // the highest priority, the first among equals
+	CONFLICT_FIRST   = "first"   // the first one in the settings
+
+	OVERLAP_SEARCH_STEPS = 1000 // leaps of one schedule to the other's next tick
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether both schedules would change the wallpaper of the desktop or
+ * of the lock screen.
+ */
+func (s *Schedule) ConflictsWith(other *Schedule) bool {
+	if !s.Command.ChangesWallpaper() || !other.Command.ChangesWallpaper() {
+		return false
+	}
+	return (s.Target.HasDesktop() && other.Target.HasDesktop()) ||
+		(s.Target.HasLockScreen() && other.Target.HasLockScreen())
+}
+
+/**
+ * Times within a year at which both schedules are due, as far as
+ * their triggers, calendars & windows tell; system conditions and
+ * interval schedules, which move with every change, can't be foreseen.
+ * @param examples (int) how many at most
+ */
+func (s *Schedule) OverlapsWith(other *Schedule, now time.Time, env *ScheduleEnv, examples int) []time.Time {
+	overlaps := make([]time.Time, 0, examples)
+	if s.IsInterval() || other.IsInterval() {
+		return overlaps
+	}
+	horizon := now.Add(SEARCH_HORIZON)
+	from, allow := now, true
+	for step := 0; step < OVERLAP_SEARCH_STEPS && len(overlaps) < examples && from.Before(horizon); step++ {
+		tick, err := s.NextTick(from, env, allow)
+		if err != nil || tick.After(horizon) {
+			break
+		}
+		otherTick, err := other.NextTick(tick, env, true)
+		if err != nil {
+			break
+		}
+
+		if otherTick.Equal(tick) {
+			overlaps = append(overlaps, tick)
+			from, allow = tick, false
+		} else {
+			from, allow = otherTick, true
+		}
+	}
+	return overlaps
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+func ValidateConflictPolicy(policy string) error {
+	switch policy {
+	case "", CONFLICT_ALL, CONFLICT_HIGHEST, CONFLICT_FIRST:
+		return nil
+	}
+	return fmt.Errorf("conflict policy must be %s, %s or %s, not %q", CONFLICT_ALL, CONFLICT_HIGHEST, CONFLICT_FIRST, policy)
+}
+
+/**
+ * Which of the due schedules run under the conflict policy. A
+ * schedule is dropped when one preferred to it conflicts with it;
+ * those that don't change wallpapers always run.
+ * @param due ([]int) indices of the due schedules, in settings order
+ * @returns ([]int) indices of those to run, in settings order
+ */
+func ResolveConflicts(schedules []Schedule, due []int, policy string) []int {
+	if policy == "" || policy == CONFLICT_ALL {
+		return due
+	}
+
+	preferred := slices.Clone(due)
+	if policy == CONFLICT_HIGHEST {
+		slices.SortStableFunc(preferred, func(a, b int) int {
+			return schedules[b].Priority - schedules[a].Priority
+		})
+	}
+
+	run := make([]int, 0, len(due))
+	for _, idx := range preferred {
+		if !slices.ContainsFunc(run, func(kept int) bool {
+			return schedules[kept].ConflictsWith(&schedules[idx])
+		}) {
+			run = append(run, idx)
+		}
+	}
+	slices.Sort(run)
+	return run
+}
@@ -0,0 +1,223 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Control socket of the angel daemon: one JSON request per
+ * connection, answered by one JSON reply.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CONTROL_SOCKET  = "angel.sock" // in the state directory
+	CONTROL_TIMEOUT = 30 * time.Second
+
+	CONTROL_NEXT     = "next"     // a fresh wallpaper from the same source
+	CONTROL_PREVIOUS = "previous" // back in the history
+	CONTROL_CATEGORY = "category" // from the category in the argument
+	CONTROL_PAUSE    = "pause"    // the schedules
+	CONTROL_RESUME   = "resume"
+	CONTROL_TOGGLE   = "toggle" // pause or resume
+	CONTROL_LOCK     = "lock"   // the carousel
+	CONTROL_UNLOCK   = "unlock"
+	CONTROL_STATUS   = "status"
+	CONTROL_RELOAD   = "reload" // the configuration
+	CONTROL_ACTION   = "action" // any other that changes the wallpaper or its state
+)
+
+/* ----------------------------------------------------------------
+ *				I n t e r f a c e s
+ *-----------------------------------------------------------------*/
+
+type IControlHandler interface {
+	Control(request ControlRequest) ControlReply
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type ControlRequest struct {
+	Command  string          `json:"command"`
+	Argument string          `json:"argument,omitempty"`
+	Target   WallpaperTarget `json:"target,omitempty"` // desktop (default), lockscreen, both
+	Action   Action          `json:"action,omitempty"` // of CONTROL_ACTION
+}
+
+/**
+ * The outcome of a request and the state of the daemon after it.
+ */
+type ControlReply struct {
+	Error     string           `json:"error,omitempty"`
+	Paused    bool             `json:"paused"`
+	Locked    bool             `json:"locked"`
+	Wallpaper *WallpaperStatus `json:"wallpaper,omitempty"`
+}
+
+/**
+ * Listens on the control socket of the angel daemon, there can only be
+ * one.
+ */
+type ControlServer struct {
+	listener net.Listener
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Listen on the control socket, replacing the one left behind
+ * by a daemon that died.
+ */
+func NewControlServer() (*ControlServer, error) {
+	socket, err := GetControlSocket()
+	if err != nil {
+		return nil, err
+	}
+	if IsAngelRunning() {
+		return nil, fmt.Errorf("an angel daemon is already listening on %s", socket)
+	}
+
+	// nobody else may connect in between listening and the chmod below,
+	// not even when the state directory was made by an older version
+	if err = os.Chmod(path.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(socket, 0600); err != nil { // ours only
+		listener.Close()
+		return nil, err
+	}
+	return &ControlServer{listener: listener}, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Answer requests until closed, each in its own goroutine; the
+ * handler takes turns if it must.
+ */
+func (s *ControlServer) Serve(handler IControlHandler) {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // closed
+		}
+		go serveControl(conn, handler)
+	}
+}
+
+/**
+ * Stop listening and remove the socket.
+ */
+func (s *ControlServer) Close() error {
+	return s.listener.Close()
+}
+
+/**
+ * The error of a failed request, nil if it succeeded.
+ */
+func (r *ControlReply) Err() error {
+	if r.Error == "" {
+		return nil
+	}
+	return fmt.Errorf("angel: %s", r.Error)
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+func GetControlSocket() (string, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, CONTROL_SOCKET), nil
+}
+
+/**
+ * Whether an angel daemon is listening on the control socket.
+ */
+func IsAngelRunning() bool {
+	socket, err := GetControlSocket()
+	if err != nil {
+		return false
+	}
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+/**
+ * Send a request to the running angel daemon and wait for its reply.
+ * @returns (error) when the daemon can't be reached, a failed request
+ * is told by the reply.
+ */
+func SendControl(request ControlRequest) (*ControlReply, error) {
+	socket, err := GetControlSocket()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("no angel daemon running: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(CONTROL_TIMEOUT))
+
+	if err = json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, err
+	}
+	var reply ControlReply
+	if err = json.NewDecoder(conn).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("reading the angel's reply: %w", err)
+	}
+	return &reply, nil
+}
+
+func serveControl(conn net.Conn, handler IControlHandler) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(CONTROL_TIMEOUT))
+
+	var request ControlRequest
+	var reply ControlReply
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if len(line) == 0 { // i.e. IsAngelRunning
+		return
+	}
+	if err = json.Unmarshal(line, &request); err != nil {
+		reply.Error = fmt.Sprintf("bad request: %s", err)
+	} else {
+		reply = handler.Control(request)
+	}
+
+	if err = json.NewEncoder(conn).Encode(reply); err != nil {
+		log.Printf("control reply: %s", err)
+	}
+}
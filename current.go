@@ -0,0 +1,161 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Publishes the current wallpaper for other tools (lock screens,
+ * conky, prompt scripts) in the user's state directory.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"lordofscripts/carousel/app"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	STATE_GROUP        = "goCarousel"
+	STATE_CURRENT_LINK = "current"      // symbolic link to the wallpaper
+	STATE_STATUS_FILE  = "current.json" // WallpaperStatus
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The wallpaper currently applied by goCarousel and what triggered it.
+ */
+type WallpaperStatus struct {
+	Path      string    `json:"path"`
+	Category  string    `json:"category,omitempty"`
+	Carousel  string    `json:"carousel,omitempty"`
+	Schedule  string    `json:"schedule,omitempty"` // title of the schedule, if any
+	TimeStamp time.Time `json:"timestamp"`
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+func NewWallpaperStatus(info *ChangeInfo) *WallpaperStatus {
+	return &WallpaperStatus{
+		Path:      info.Filename,
+		Category:  info.Category,
+		Carousel:  info.Carousel,
+		Schedule:  info.Schedule,
+		TimeStamp: time.Now(),
+	}
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Write the status file and point the stable 'current' link at the
+ * wallpaper. Both are replaced atomically so readers never see a
+ * half-written file or a missing link.
+ */
+func (s *WallpaperStatus) Publish() error {
+	dir, err := GetStateDir()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	statusFile := path.Join(dir, STATE_STATUS_FILE)
+	if err = os.WriteFile(statusFile+".tmp", data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(statusFile+".tmp", statusFile); err != nil {
+		return err
+	}
+
+	target, err := filepath.Abs(s.Path)
+	if err != nil {
+		return err
+	}
+
+	link := path.Join(dir, STATE_CURRENT_LINK)
+	os.Remove(link + ".tmp")
+	if err = os.Symlink(target, link+".tmp"); err != nil {
+		return err
+	}
+	return os.Rename(link+".tmp", link)
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Get (and create) our per-user state directory, i.e.
+ * ~/.local/state/goCarousel. It is ours only, the control socket of
+ * the angel lives there.
+ */
+func GetStateDir() (string, error) {
+	dir := path.Join(app.GetUserStateDir(), STATE_GROUP)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+/**
+ * Read back the last published wallpaper status.
+ */
+func GetWallpaperStatus() (*WallpaperStatus, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path.Join(dir, STATE_STATUS_FILE))
+	if err != nil {
+		return nil, err
+	}
+
+	var status WallpaperStatus
+	if err = json.Unmarshal(data, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+/**
+ * Tell the user which wallpaper goCarousel last applied.
+ */
+func PrintWallpaperStatus() {
+	status, err := GetWallpaperStatus()
+	if err != nil {
+		fmt.Println("Current wallpaper unknown")
+		return
+	}
+
+	fmt.Printf("Current wallpaper: %s\n", status.Path)
+	if status.Carousel != "" {
+		fmt.Printf("\tCarousel: %s\n", status.Carousel)
+	}
+	if status.Category != "" {
+		fmt.Printf("\tCategory: %s\n", status.Category)
+	}
+	if status.Schedule != "" {
+		fmt.Printf("\tSchedule: %s\n", status.Schedule)
+	}
+	fmt.Printf("\tSince: %s\n", status.TimeStamp.Format(time.DateTime))
+}
@@ -0,0 +1,208 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Dynamic wallpapers: a set of frames bound to times of day or to
+ * the position of the sun.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	PHASE_DAWN    = "dawn"
+	PHASE_MORNING = "morning"
+	PHASE_NOON    = "noon"
+	PHASE_DUSK    = "dusk"
+	PHASE_NIGHT   = "night"
+
+	CLOCK_FORMAT = "15:04"
+)
+
+// the solar event of each phase, and its clock time when we don't
+// know where we are (or the sun doesn't rise or set that day)
+var dynamicPhases = map[string]struct {
+	event string
+	clock string
+}{
+	PHASE_DAWN:    {SUN_CIVIL_DAWN, "06:00"},
+	PHASE_MORNING: {SUN_SUNRISE, "07:00"},
+	PHASE_NOON:    {SUN_NOON, "12:00"},
+	PHASE_DUSK:    {SUN_SUNSET, "18:00"},
+	PHASE_NIGHT:   {SUN_CIVIL_DUSK, "19:00"},
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The frames of a dynamic wallpaper as given in its JSON manifest.
+ * Each frame is shown from its start until that of the next one.
+ */
+type DynamicWallpaper struct {
+	Name   string         `json:"name,omitempty"`
+	Frames []DynamicFrame `json:"frames"`
+}
+
+/**
+ * A frame starts either at a time of day (At) or when the sun passes
+ * an elevation in the morning (Rising) or afternoon.
+ */
+type DynamicFrame struct {
+	File      string   `json:"file"`                // relative to the manifest
+	At        string   `json:"at,omitempty"`        // dawn, morning, noon, dusk, night, a solar event or HH:MM
+	Elevation *float64 `json:"elevation,omitempty"` // degrees above the horizon
+	Rising    bool     `json:"rising,omitempty"`
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Load the manifest of a dynamic wallpaper.
+ */
+func NewDynamicWallpaper(manifest string) (*DynamicWallpaper, error) {
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamic := &DynamicWallpaper{}
+	if err = json.Unmarshal(data, dynamic); err != nil {
+		return nil, NewAppErrorf(ErrInvalidDynamic, "%s: %s", manifest, err)
+	}
+	if len(dynamic.Frames) == 0 {
+		return nil, NewAppErrorf(ErrInvalidDynamic, "%s has no frames", manifest)
+	}
+
+	base := filepath.Dir(manifest)
+	for idx := range dynamic.Frames {
+		if file := dynamic.Frames[idx].File; file != "" && !filepath.IsAbs(file) {
+			dynamic.Frames[idx].File = filepath.Join(base, file)
+		}
+	}
+	return dynamic, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Check that every frame exists and has a start we can compute, which
+ * for solar events & elevations means knowing our location.
+ */
+func (d *DynamicWallpaper) Validate(where *GeoLocation) error {
+	for idx, frame := range d.Frames {
+		if !FileExists(frame.File) {
+			return NewAppErrorf(ErrInvalidDynamic, "frame #%d: missing %q", idx+1, frame.File)
+		}
+
+		_, isPhase := dynamicPhases[frame.At]
+		_, clockErr := time.Parse(CLOCK_FORMAT, frame.At)
+		switch {
+		case frame.Elevation != nil:
+			if frame.At != "" {
+				return NewAppErrorf(ErrInvalidDynamic, "frame #%d has both a time and an elevation", idx+1)
+			}
+			if where == nil {
+				return NewAppErrorf(ErrInvalidDynamic, "frame #%d: an elevation needs options.location", idx+1)
+			}
+		case isPhase || clockErr == nil:
+		case IsSolarEvent(frame.At):
+			if where == nil {
+				return NewAppErrorf(ErrInvalidDynamic, "frame #%d: %s needs options.location", idx+1, frame.At)
+			}
+		default:
+			return NewAppErrorf(ErrInvalidDynamic, "frame #%d: unknown time %q", idx+1, frame.At)
+		}
+	}
+	return nil
+}
+
+/**
+ * The frame to show at the given time: the one that started last.
+ * Before the first start of the day it is the last one of yesterday.
+ */
+func (d *DynamicWallpaper) FrameAt(now time.Time, where *GeoLocation) string {
+	var best string
+	var bestStart time.Time
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		for _, frame := range d.Frames {
+			start, ok := frame.startOn(day, where)
+			if ok && !start.After(now) && (best == "" || start.After(bestStart)) {
+				best, bestStart = frame.File, start
+			}
+		}
+	}
+
+	if best == "" { // nothing we can place in time, i.e. polar night
+		return d.Frames[0].File
+	}
+	return best
+}
+
+/**
+ * All frames in the order of the manifest.
+ */
+func (d *DynamicWallpaper) Files() []string {
+	files := make([]string, 0, len(d.Frames))
+	for _, frame := range d.Frames {
+		if !slices.Contains(files, frame.File) {
+			files = append(files, frame.File)
+		}
+	}
+	return files
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * When the frame starts on the given day.
+ * @returns (bool) false if it doesn't that day
+ */
+func (f *DynamicFrame) startOn(day time.Time, where *GeoLocation) (time.Time, bool) {
+	if f.Elevation != nil {
+		if where == nil {
+			return time.Time{}, false
+		}
+		return where.CrossingOn(day, *f.Elevation, f.Rising)
+	}
+
+	clock := f.At
+	if phase, isPhase := dynamicPhases[f.At]; isPhase {
+		if where != nil {
+			if start, ok := where.EventOn(phase.event, day); ok {
+				return start, true
+			}
+		}
+		clock = phase.clock
+	} else if IsSolarEvent(f.At) {
+		if where == nil {
+			return time.Time{}, false
+		}
+		return where.EventOn(f.At, day)
+	}
+
+	hhmm, err := time.Parse(CLOCK_FORMAT, clock)
+	if err != nil {
+		return time.Time{}, false
+	}
+	y, m, dd := day.Date()
+	return time.Date(y, m, dd, hhmm.Hour(), hhmm.Minute(), 0, 0, day.Location()), true
+}
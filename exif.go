@@ -0,0 +1,229 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * EXIF date & orientation of (JPEG) photos.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	EXIF_DATE_FORMAT = "2006:01:02 15:04:05"
+	ORIENT_NORMAL    = 1
+
+	jpegMarkerAPP1       = 0xe1
+	exifTagOrientation   = 0x0112
+	exifTagDateTime      = 0x0132
+	exifTagExifIFD       = 0x8769
+	exifTagDateTimeOrig  = 0x9003
+	exifTypeShort        = 3
+	exifIfdEntrySize     = 12
+	exifMaxIfdEntryCount = 512 // sanity limit for corrupt files
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type ExifInfo struct {
+	Taken       time.Time // DateTimeOriginal, else DateTime (local)
+	Orientation int       // 1..8, 1 is upright
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The EXIF date & orientation of a photo. Files without EXIF data
+ * (i.e. PNG) are upright and have a zero date.
+ */
+func ReadExif(filename string) ExifInfo {
+	head, err := readFileHead(filename)
+	if err != nil {
+		return ExifInfo{Orientation: ORIENT_NORMAL}
+	}
+	return exifOf(head)
+}
+
+/**
+ * The EXIF data among the first bytes of a JPEG image.
+ */
+func exifOf(head []byte) ExifInfo {
+	info := ExifInfo{Orientation: ORIENT_NORMAL}
+	for _, segment := range jpegSegments(head, jpegMarkerAPP1) {
+		if tiff, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok {
+			parseTiff(tiff, &info)
+			break
+		}
+	}
+
+	return info
+}
+
+/**
+ * The payload of all JPEG segments with the given marker that precede
+ * the image data.
+ */
+func jpegSegments(data []byte, marker byte) [][]byte {
+	segments := make([][]byte, 0)
+	if len(data) < 4 || data[0] != 0xff || data[1] != jpegMarkerSOI {
+		return segments
+	}
+
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xff {
+		current := data[pos+1]
+		if current == jpegMarkerSOS {
+			break
+		}
+
+		size := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + size
+		if size < 2 || end > len(data) {
+			break
+		}
+
+		if current == marker {
+			segments = append(segments, data[pos+4:end])
+		}
+		pos = end
+	}
+
+	return segments
+}
+
+func parseTiff(tiff []byte, info *ExifInfo) {
+	if len(tiff) < 8 {
+		return
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	var dateTime, dateTimeOriginal string
+	var exifIfd uint32
+	readIfd(tiff, order, order.Uint32(tiff[4:]), func(tag, kind uint16, count, value uint32, raw []byte) {
+		switch tag {
+		case exifTagOrientation:
+			if kind == exifTypeShort {
+				info.Orientation = int(order.Uint16(raw))
+			}
+		case exifTagDateTime:
+			dateTime = tiffString(tiff, count, value)
+		case exifTagExifIFD:
+			exifIfd = value
+		}
+	})
+
+	if exifIfd != 0 {
+		readIfd(tiff, order, exifIfd, func(tag, kind uint16, count, value uint32, raw []byte) {
+			if tag == exifTagDateTimeOrig {
+				dateTimeOriginal = tiffString(tiff, count, value)
+			}
+		})
+	}
+
+	if info.Orientation < 1 || info.Orientation > 8 {
+		info.Orientation = ORIENT_NORMAL
+	}
+
+	for _, stamp := range []string{dateTimeOriginal, dateTime} {
+		if taken, err := time.ParseInLocation(EXIF_DATE_FORMAT, stamp, time.Local); err == nil {
+			info.Taken = taken
+			break
+		}
+	}
+}
+
+/**
+ * Visit the entries of the Image File Directory at the given offset.
+ */
+func readIfd(tiff []byte, order binary.ByteOrder, offset uint32, visit func(tag, kind uint16, count, value uint32, raw []byte)) {
+	if int(offset)+2 > len(tiff) {
+		return
+	}
+
+	count := int(order.Uint16(tiff[offset:]))
+	if count > exifMaxIfdEntryCount {
+		return
+	}
+
+	entries := tiff[offset+2:]
+	for i := 0; i < count && (i+1)*exifIfdEntrySize <= len(entries); i++ {
+		entry := entries[i*exifIfdEntrySize:]
+		visit(order.Uint16(entry), order.Uint16(entry[2:]), order.Uint32(entry[4:]), order.Uint32(entry[8:]), entry[8:12])
+	}
+}
+
+/**
+ * An ASCII value stored elsewhere in the TIFF block.
+ */
+func tiffString(tiff []byte, count, offset uint32) string {
+	if uint64(offset)+uint64(count) > uint64(len(tiff)) {
+		return ""
+	}
+	return strings.TrimRight(string(tiff[offset:offset+count]), "\x00 ")
+}
+
+/**
+ * Transform an image as told by its EXIF orientation so that it
+ * looks upright.
+ */
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= ORIENT_NORMAL || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	dw, dh := w, h
+	if orientation >= 5 { // rotated a quarter turn
+		dw, dh = h, w
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored
+				sx, sy = w-1-x, y
+			case 3: // upside down
+				sx, sy = w-1-x, h-1-y
+			case 4: // flipped
+				sx, sy = x, h-1-y
+			case 5: // transposed
+				sx, sy = y, x
+			case 6: // needs 90° clockwise
+				sx, sy = y, h-1-x
+			case 7: // transversed
+				sx, sy = w-1-y, h-1-x
+			case 8: // needs 90° counter-clockwise
+				sx, sy = w-1-y, x
+			}
+			out.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+
+	return out
+}
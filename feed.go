@@ -0,0 +1,582 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Image feeds (RSS, Atom, JSON) as categories. Their images are kept
+ * in our cache so that we can do without the network.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CACHE_FEED_DIR    = "feeds"
+	FEED_STATE_FILE   = ".feed.json"
+	FEED_REFRESH      = time.Hour
+	FEED_MAX_ITEMS    = 30
+	FEED_MAX_CACHE_MB = 200
+	FEED_MAX_DOCUMENT = 4 << 20  // bytes
+	FEED_MAX_IMAGE    = 64 << 20 // bytes
+	FEED_TIMEOUT      = 30 * time.Second
+)
+
+// Used by feed categories unless the wallpaper manager is given
+// another one, i.e. to talk to a local stand-in server.
+var DefaultFeedClient = &http.Client{Timeout: FEED_TIMEOUT}
+
+var feedImageTypes = map[string]string{
+	"image/jpeg":    ".jpg",
+	"image/png":     ".png",
+	"image/svg+xml": ".svg",
+}
+
+// keys holding the image in plain JSON documents, best first
+var jsonImageKeys = []string{"hdurl", "image", "url", "src"}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type FeedItem struct {
+	URL  string `json:"url"`
+	File string `json:"file"` // name in the feed cache directory
+	Size int64  `json:"size"`
+}
+
+/**
+ * What we know about a feed between runs.
+ */
+type FeedState struct {
+	ETag         string     `json:"etag,omitempty"`
+	LastModified string     `json:"last_modified,omitempty"`
+	Fetched      time.Time  `json:"fetched"`
+	Items        []FeedItem `json:"items"` // newest first
+}
+
+/**
+ * Keeps the cache of a feed up to date.
+ */
+type FeedFetcher struct {
+	Client *http.Client
+	source *FeedSource
+	dir    string
+	state  FeedState
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type xmlFeed struct {
+	Channel struct {
+		Items []xmlItem `xml:"item"`
+	} `xml:"channel"` // RSS 2.0
+	Items   []xmlItem `xml:"item"`  // RSS 1.0
+	Entries []xmlItem `xml:"entry"` // Atom
+}
+
+type xmlItem struct {
+	Links      []xmlLink  `xml:"link"`
+	Enclosures []xmlMedia `xml:"enclosure"`
+	Media      []xmlMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	Groups     []struct {
+		Media []xmlMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	} `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type xmlLink struct {
+	Href string `xml:"href,attr"` // Atom
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"` // RSS
+}
+
+type xmlMedia struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Fetcher of a feed with its cache loaded.
+ * @param client (*http.Client) nil for the DefaultFeedClient
+ */
+func NewFeedFetcher(source *FeedSource, client *http.Client) (*FeedFetcher, error) {
+	if client == nil {
+		client = DefaultFeedClient
+	}
+
+	dir, err := feedCacheDir(source.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &FeedFetcher{Client: client, source: source, dir: dir}
+	data, err := os.ReadFile(path.Join(dir, FEED_STATE_FILE))
+	if err == nil {
+		if err = json.Unmarshal(data, &f.state); err != nil {
+			log.Printf("forgetting damaged feed state of %s: %s", source.URL, err)
+			f.state = FeedState{}
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Check the feed settings.
+ */
+func (s *FeedSource) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewAppErrorf(ErrInvalidFeed, "feed URL must be http(s): %q", s.URL)
+	}
+
+	if s.Refresh != "" {
+		if _, err := time.ParseDuration(s.Refresh); err != nil {
+			return NewAppErrorf(ErrInvalidFeed, "refresh: %s", err)
+		}
+	}
+	return nil
+}
+
+/**
+ * The directory where the images of the feed are kept.
+ */
+func (f *FeedFetcher) Dir() string {
+	return f.dir
+}
+
+/**
+ * Cached images of the feed, newest first.
+ */
+func (f *FeedFetcher) Files() []string {
+	files := make([]string, 0, len(f.state.Items))
+	for _, item := range f.state.Items {
+		filename := path.Join(f.dir, item.File)
+		if FileExists(filename) {
+			files = append(files, filename)
+		}
+	}
+	return files
+}
+
+/**
+ * Fetch the feed when it is due (or forced) and download its new
+ * images. When the feed can't be reached we make do with what is
+ * cached; that is only an error if there is nothing cached.
+ */
+func (f *FeedFetcher) Sync(force bool) error {
+	if !force && time.Since(f.state.Fetched) < f.source.refreshInterval() {
+		return nil
+	}
+
+	urls, err := f.fetchFeed()
+	if err != nil {
+		if len(f.Files()) > 0 {
+			log.Printf("feed %s unavailable, using cached images: %s", f.source.URL, err)
+			return nil
+		}
+		return err
+	}
+
+	if urls != nil { // nil means not modified
+		f.update(urls)
+	}
+	f.state.Fetched = time.Now()
+	return f.save()
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (s *FeedSource) refreshInterval() time.Duration {
+	if interval, err := time.ParseDuration(s.Refresh); err == nil && interval > 0 {
+		return interval
+	}
+	return FEED_REFRESH
+}
+
+/**
+ * Get the image URLs in the feed using a conditional request.
+ * @returns (nil, nil) if the feed didn't change
+ */
+func (f *FeedFetcher) fetchFeed() ([]string, error) {
+	req, err := http.NewRequest(http.MethodGet, f.source.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", "goCarousel/"+MANUAL_VERSION)
+	if len(f.state.Items) > 0 {
+		if f.state.ETag != "" {
+			req.Header.Set("If-None-Match", f.state.ETag)
+		}
+		if f.state.LastModified != "" {
+			req.Header.Set("If-Modified-Since", f.state.LastModified)
+		}
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAppErrorf(ErrInvalidFeed, "%s: %s", f.source.URL, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, FEED_MAX_DOCUMENT))
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := parseFeed(body, resp.Request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	f.state.ETag = resp.Header.Get("ETag")
+	f.state.LastModified = resp.Header.Get("Last-Modified")
+	return urls, nil
+}
+
+/**
+ * Download the images we don't have yet and drop those beyond the
+ * limits, the oldest first. Images no longer in the feed are kept
+ * while there is room for them.
+ */
+func (f *FeedFetcher) update(urls []string) {
+	maxItems := f.source.MaxItems
+	if maxItems <= 0 {
+		maxItems = FEED_MAX_ITEMS
+	}
+	maxBytes := int64(f.source.MaxCacheMB)
+	if maxBytes <= 0 {
+		maxBytes = FEED_MAX_CACHE_MB
+	}
+	maxBytes <<= 20
+
+	known := make(map[string]FeedItem)
+	for _, item := range f.state.Items {
+		known[item.URL] = item
+	}
+
+	items := make([]FeedItem, 0)
+	for _, imageURL := range urls {
+		if len(items) >= maxItems {
+			break
+		}
+
+		item, ok := known[imageURL]
+		if ok && FileExists(path.Join(f.dir, item.File)) {
+			delete(known, imageURL)
+		} else {
+			var err error
+			if item, err = f.download(imageURL); err != nil {
+				log.Printf("feed image %s: %s", imageURL, err)
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	for _, item := range f.state.Items {
+		if _, old := known[item.URL]; old {
+			items = append(items, item)
+		}
+	}
+
+	var total int64
+	kept := make([]FeedItem, 0, len(items))
+	for _, item := range items {
+		total += item.Size
+		if len(kept) < maxItems && total <= maxBytes {
+			kept = append(kept, item)
+		} else {
+			os.Remove(path.Join(f.dir, item.File))
+		}
+	}
+
+	f.state.Items = kept
+}
+
+/**
+ * Download a feed image into the cache. Only image types we can use
+ * as wallpaper are accepted.
+ */
+func (f *FeedFetcher) download(imageURL string) (FeedItem, error) {
+	item := FeedItem{URL: imageURL}
+	req, err := http.NewRequest(http.MethodGet, imageURL, nil)
+	if err != nil {
+		return item, err
+	}
+	req.Header.Set("User-Agent", "goCarousel/"+MANUAL_VERSION)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return item, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return item, fmt.Errorf("%s", resp.Status)
+	}
+
+	mimeType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	ext, ok := feedImageTypes[strings.TrimSpace(strings.ToLower(mimeType))]
+	if !ok {
+		return item, fmt.Errorf("unsupported content type %q", mimeType)
+	}
+
+	sum := md5.Sum([]byte(imageURL))
+	item.File = hex.EncodeToString(sum[:]) + ext
+	filename := path.Join(f.dir, item.File)
+
+	fdOut, err := os.Create(filename + ".tmp")
+	if err != nil {
+		return item, err
+	}
+
+	item.Size, err = io.Copy(fdOut, io.LimitReader(resp.Body, FEED_MAX_IMAGE+1))
+	if cerr := fdOut.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && item.Size > FEED_MAX_IMAGE {
+		err = fmt.Errorf("larger than %d MB", FEED_MAX_IMAGE>>20)
+	}
+	if err != nil {
+		os.Remove(filename + ".tmp")
+		return item, err
+	}
+
+	return item, os.Rename(filename+".tmp", filename)
+}
+
+func (f *FeedFetcher) save() error {
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	filename := path.Join(f.dir, FEED_STATE_FILE)
+	if err = os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+/**
+ * The (first) image of a feed item: an enclosure, Media RSS content
+ * or a link to an image.
+ */
+func (item *xmlItem) image() string {
+	media := slices.Concat(item.Enclosures, item.Media)
+	for _, group := range item.Groups {
+		media = append(media, group.Media...)
+	}
+	for _, m := range media {
+		if m.Medium == "image" || strings.HasPrefix(m.Type, "image/") || (m.Type == "" && looksLikeImage(m.URL)) {
+			return m.URL
+		}
+	}
+
+	for _, link := range item.Links {
+		href := link.Href
+		if href == "" {
+			href = link.Text
+		}
+		if strings.HasPrefix(link.Type, "image/") || looksLikeImage(href) {
+			return href
+		}
+	}
+	return ""
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The cache directory of a feed.
+ */
+func feedCacheDir(feedURL string) (string, error) {
+	sum := md5.Sum([]byte(feedURL))
+	return getAppCacheDir(path.Join(CACHE_FEED_DIR, hex.EncodeToString(sum[:])))
+}
+
+/**
+ * The image URLs (absolute, unique) of an RSS, Atom, JSON Feed or
+ * a plain JSON document, in document order.
+ */
+func parseFeed(body []byte, base *url.URL) ([]string, error) {
+	var found []string
+	var err error
+
+	trimmed := bytes.TrimSpace(body)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("[")) {
+		found, err = parseJSONFeed(trimmed)
+	} else {
+		found, err = parseXMLFeed(trimmed)
+	}
+	if err != nil {
+		return nil, NewAppErrorf(ErrInvalidFeed, "%s: %s", base, err)
+	}
+
+	urls := make([]string, 0, len(found))
+	for _, ref := range found {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		if abs := u.String(); !slices.Contains(urls, abs) {
+			urls = append(urls, abs)
+		}
+	}
+	return urls, nil
+}
+
+func parseXMLFeed(body []byte) ([]string, error) {
+	var feed xmlFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0)
+	for _, item := range slices.Concat(feed.Channel.Items, feed.Items, feed.Entries) {
+		if image := item.image(); image != "" {
+			urls = append(urls, image)
+		}
+	}
+	return urls, nil
+}
+
+/**
+ * JSON Feed (jsonfeed.org) items or, for any other JSON document (i.e.
+ * a "picture of the day" endpoint), every object holding an image URL.
+ */
+func parseJSONFeed(body []byte) ([]string, error) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+
+	urls := make([]string, 0)
+	if m, ok := doc.(map[string]any); ok {
+		if version, _ := m["version"].(string); strings.Contains(version, "jsonfeed.org") {
+			items, _ := m["items"].([]any)
+			for _, it := range items {
+				if item, ok := it.(map[string]any); ok {
+					if image := jsonFeedImage(item); image != "" {
+						urls = append(urls, image)
+					}
+				}
+			}
+			return urls, nil
+		}
+	}
+
+	collectJSONImages(doc, &urls)
+	return urls, nil
+}
+
+func jsonFeedImage(item map[string]any) string {
+	attachments, _ := item["attachments"].([]any)
+	for _, a := range attachments {
+		if attachment, ok := a.(map[string]any); ok {
+			mimeType, _ := attachment["mime_type"].(string)
+			if u, _ := attachment["url"].(string); u != "" && strings.HasPrefix(mimeType, "image/") {
+				return u
+			}
+		}
+	}
+
+	for _, key := range []string{"image", "banner_image"} {
+		if u, _ := item[key].(string); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+/**
+ * Walk a JSON value taking (at most) one image from every object.
+ */
+func collectJSONImages(value any, urls *[]string) {
+	switch v := value.(type) {
+	case []any:
+		for _, elem := range v {
+			collectJSONImages(elem, urls)
+		}
+
+	case map[string]any:
+		for _, key := range jsonImageKeys {
+			if u, _ := v[key].(string); u != "" && (key == "hdurl" || key == "image" || looksLikeImage(u)) {
+				*urls = append(*urls, u)
+				break
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			collectJSONImages(v[key], urls)
+		}
+	}
+}
+
+/**
+ * Whether a URL seems to point at an image we can use.
+ */
+func looksLikeImage(ref string) bool {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return false
+	}
+
+	if isWallpaperFile(path.Base(u.Path)) {
+		return true
+	}
+	query := strings.ToLower(u.RawQuery)
+	return strings.Contains(query, ".jpg") || strings.Contains(query, ".jpeg") || strings.Contains(query, ".png")
+}
@@ -0,0 +1,170 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Bounded history of applied wallpapers.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	STATE_HISTORY_FILE   = "history.json"
+	DEFAULT_HISTORY_SIZE = 50
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type HistoryEntry struct {
+	Path      string    `json:"path"`
+	Category  string    `json:"category,omitempty"`
+	Carousel  string    `json:"carousel,omitempty"`
+	Schedule  string    `json:"schedule,omitempty"`
+	TimeStamp time.Time `json:"timestamp"`
+}
+
+/**
+ * Applied wallpapers, oldest first. The cursor points at the entry
+ * being shown, which is the newest one unless we went back with
+ * Previous(). What was shown before the last change is kept so that
+ * it can be undone.
+ */
+type WallpaperHistory struct {
+	Entries []HistoryEntry `json:"entries"`
+	Cursor  int            `json:"cursor"`
+	Before  int            `json:"before"`          // cursor before the last change, -1 if it can't be undone
+	Added   bool           `json:"added,omitempty"` // the last change added the newest entry
+	limit   int
+	file    string
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Load the wallpaper history from the state directory. A
+ * missing history file is simply an empty history.
+ * @param limit (int) maximum number of entries kept, 0 for the default.
+ */
+func NewWallpaperHistory(limit int) (*WallpaperHistory, error) {
+	if limit <= 0 {
+		limit = DEFAULT_HISTORY_SIZE
+	}
+
+	dir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	h := &WallpaperHistory{Entries: []HistoryEntry{}, Cursor: -1, Before: -1, limit: limit, file: path.Join(dir, STATE_HISTORY_FILE)}
+	data, err := os.ReadFile(h.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, h); err != nil {
+		return nil, err
+	}
+	if h.Cursor < 0 || h.Cursor >= len(h.Entries) {
+		h.Cursor = len(h.Entries) - 1
+	}
+	if h.Before >= len(h.Entries) {
+		h.Before = -1
+	}
+
+	return h, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Append a freshly applied wallpaper, dropping the oldest entries
+ * beyond the limit. It becomes the current one.
+ */
+func (h *WallpaperHistory) Record(info *ChangeInfo) {
+	h.Before, h.Added = h.Cursor, true
+	h.Entries = append(h.Entries, HistoryEntry{
+		Path:      info.Filename,
+		Category:  info.Category,
+		Carousel:  info.Carousel,
+		Schedule:  info.Schedule,
+		TimeStamp: time.Now(),
+	})
+
+	if excess := len(h.Entries) - h.limit; excess > 0 {
+		h.Entries = h.Entries[excess:]
+		h.Before = max(h.Before-excess, -1)
+	}
+	h.Cursor = len(h.Entries) - 1
+}
+
+/**
+ * The entry being shown, nil if there is no history.
+ */
+func (h *WallpaperHistory) Current() *HistoryEntry {
+	if h.Cursor < 0 || h.Cursor >= len(h.Entries) {
+		return nil
+	}
+	return &h.Entries[h.Cursor]
+}
+
+/**
+ * Step back to the entry before the current one.
+ * @returns nil if already at the oldest entry
+ */
+func (h *WallpaperHistory) Previous() *HistoryEntry {
+	if h.Cursor <= 0 {
+		return nil
+	}
+
+	h.Before, h.Added = h.Cursor, false
+	h.Cursor--
+	return &h.Entries[h.Cursor]
+}
+
+/**
+ * Undo the last change: make the entry shown before it current again,
+ * forgetting the newest entry if the change added it.
+ * @returns nil if there is nothing to go back to
+ */
+func (h *WallpaperHistory) Undo() *HistoryEntry {
+	if h.Before < 0 || h.Before >= len(h.Entries) {
+		return nil
+	}
+
+	if h.Added {
+		h.Entries = h.Entries[:len(h.Entries)-1]
+	}
+	h.Cursor, h.Before, h.Added = h.Before, -1, false
+	return &h.Entries[h.Cursor]
+}
+
+func (h *WallpaperHistory) Save() error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(h.file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(h.file+".tmp", h.file)
+}
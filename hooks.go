@@ -0,0 +1,136 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * User hooks run before & after a wallpaper change.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	HOOK_PRE_CHANGE  = "pre_change"
+	HOOK_POST_CHANGE = "post_change"
+	HOOK_TIMEOUT     = 30 * time.Second // unless the hooks tell otherwise
+	HOOK_WAIT_DELAY  = 2 * time.Second  // for whatever a killed hook left holding its output
+
+	// Environment variables describing the change
+	ENV_HOOK_EVENT    = "GOCAROUSEL_EVENT"
+	ENV_HOOK_FILE     = "GOCAROUSEL_FILE"
+	ENV_HOOK_CATEGORY = "GOCAROUSEL_CATEGORY"
+	ENV_HOOK_CAROUSEL = "GOCAROUSEL_CAROUSEL"
+	ENV_HOOK_SCHEDULE = "GOCAROUSEL_SCHEDULE"
+	ENV_HOOK_TARGET   = "GOCAROUSEL_TARGET" // desktop, lockscreen, both
+	ENV_HOOK_SCHEME   = "GOCAROUSEL_COLOR_SCHEME"
+	ENV_HOOK_PALETTE  = "GOCAROUSEL_PALETTE"  // path to the palette JSON
+	ENV_HOOK_DOMINANT = "GOCAROUSEL_DOMINANT" // #rrggbb
+	ENV_HOOK_COLORS   = "GOCAROUSEL_COLORS"   // space-separated #rrggbb
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * What is known about a wallpaper change. It is exported to the hook
+ * commands as environment variables.
+ */
+type ChangeInfo struct {
+	Filename    string
+	Category    string
+	Carousel    string
+	Schedule    string // title of the triggering schedule
+	Target      string
+	ColorScheme string
+	PaletteFile string
+	Palette     *Palette
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * How long a hook may run before it is killed.
+ */
+func (h *HookOpts) Deadline() time.Duration {
+	if h.Timeout == "" {
+		return HOOK_TIMEOUT
+	}
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil || timeout <= 0 {
+		log.Printf("hooks timeout must be a positive duration like 10s, not %q", h.Timeout)
+		return HOOK_TIMEOUT
+	}
+	return timeout
+}
+
+func (ci *ChangeInfo) Environ(event string) []string {
+	env := []string{
+		ENV_HOOK_EVENT + "=" + event,
+		ENV_HOOK_FILE + "=" + ci.Filename,
+		ENV_HOOK_CATEGORY + "=" + ci.Category,
+		ENV_HOOK_CAROUSEL + "=" + ci.Carousel,
+		ENV_HOOK_SCHEDULE + "=" + ci.Schedule,
+		ENV_HOOK_TARGET + "=" + ci.Target,
+		ENV_HOOK_SCHEME + "=" + strings.Trim(strings.TrimSpace(ci.ColorScheme), "'"),
+	}
+
+	if ci.Palette != nil {
+		colors := make([]string, len(ci.Palette.Colors))
+		for idx, c := range ci.Palette.Colors {
+			colors[idx] = c.Hex
+		}
+		env = append(env,
+			ENV_HOOK_PALETTE+"="+ci.PaletteFile,
+			ENV_HOOK_DOMINANT+"="+ci.Palette.Dominant,
+			ENV_HOOK_COLORS+"="+strings.Join(colors, " "),
+		)
+	}
+
+	return env
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Run each of the hook command lines through the shell, in order.
+ * A failing hook, or one killed for taking longer than the timeout, is
+ * logged and does not prevent the others nor the wallpaper change from
+ * happening.
+ */
+func RunHooks(event string, commands []string, timeout time.Duration, info *ChangeInfo) {
+	if len(commands) == 0 {
+		return
+	}
+
+	env := append(os.Environ(), info.Environ(event)...)
+	for idx, command := range commands {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		cmd := exec.CommandContext(ctx, EXT_SHELL, EXT_SHELL_CMD, command)
+		cmd.Env = env
+		cmd.WaitDelay = HOOK_WAIT_DELAY
+		out, err := cmd.CombinedOutput()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			log.Printf("%s hook #%d '%s' killed after %s", event, idx+1, command, timeout)
+		} else if err != nil {
+			log.Printf("%s hook #%d '%s' failed: %s %s", event, idx+1, command, err, strings.TrimSpace(string(out)))
+		}
+		cancel()
+	}
+}
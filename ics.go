@@ -0,0 +1,780 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Local iCalendar (.ics) files: events and their recurrences (RRULE).
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	ICS_DATE_FORMAT     = "20060102"
+	ICS_DATETIME_FORMAT = "20060102T150405"
+
+	icsMaxPeriods = 100000 // sanity limit when expanding recurrences
+)
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The events of one or more calendar files. Events we can't expand
+ * (unsupported recurrence rules) are listed in Skipped.
+ */
+type Calendar struct {
+	Events  []*CalendarEvent
+	Skipped []string
+}
+
+type CalendarEvent struct {
+	UID        string
+	Summary    string
+	Categories []string
+	Start      time.Time
+	AllDay     bool
+	Days       int           // length of all-day events
+	Duration   time.Duration // length of the others
+	rule       *recurrenceRule
+	exDates    []time.Time
+	rDates     []time.Time
+	recurrence time.Time // RECURRENCE-ID of an overriding instance
+	end        time.Time // as read, resolved by finish()
+	length     time.Duration
+	rruleText  string
+}
+
+/**
+ * A single instance of a (recurring) event.
+ */
+type CalendarOccurrence struct {
+	Event *CalendarEvent
+	Start time.Time
+	End   time.Time
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type recurrenceRule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []int
+	byMonthDay []int
+	byDay      []weekdayNum
+	bySetPos   []int
+	weekStart  time.Weekday
+}
+
+// i.e. MO, 2TU, -1SU
+type weekdayNum struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Load the events of the given .ics files.
+ */
+func NewCalendar(files []string) (*Calendar, error) {
+	calendar := &Calendar{Events: make([]*CalendarEvent, 0), Skipped: make([]string, 0)}
+	for _, file := range files {
+		fd, err := os.Open(file)
+		if err != nil {
+			return calendar, err
+		}
+
+		events, skipped, err := ParseICS(fd)
+		fd.Close()
+		if err != nil {
+			return calendar, fmt.Errorf("%s: %w", file, err)
+		}
+		calendar.Events = append(calendar.Events, events...)
+		for _, reason := range skipped {
+			calendar.Skipped = append(calendar.Skipped, file+": "+reason)
+		}
+	}
+	return calendar, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The occurrences of events matching the condition that overlap the
+ * given period, in order of start.
+ */
+func (c *Calendar) Matching(match *CalendarMatch, from, until time.Time) []CalendarOccurrence {
+	found := make([]CalendarOccurrence, 0)
+	for _, event := range c.Events {
+		if match.Matches(event) {
+			found = append(found, event.Between(from, until)...)
+		}
+	}
+
+	slices.SortFunc(found, func(a, b CalendarOccurrence) int { return a.Start.Compare(b.Start) })
+	return found
+}
+
+/**
+ * The occurrences of the event that overlap the given period.
+ */
+func (e *CalendarEvent) Between(from, until time.Time) []CalendarOccurrence {
+	found := make([]CalendarOccurrence, 0)
+	for _, start := range e.startsUntil(until) {
+		occurrence := CalendarOccurrence{Event: e, Start: start, End: e.endOf(start)}
+		if occurrence.Overlaps(from, until) {
+			found = append(found, occurrence)
+		}
+	}
+	return found
+}
+
+/**
+ * Whether the occurrence takes place (at least partly) in the period.
+ * Events without duration count at their start.
+ */
+func (o CalendarOccurrence) Overlaps(from, until time.Time) bool {
+	return o.Start.Before(until) && (o.End.After(from) || o.Start.Equal(from))
+}
+
+func (o CalendarOccurrence) String() string {
+	if o.Event.AllDay {
+		last := o.End.AddDate(0, 0, -1)
+		if last.After(o.Start) {
+			return fmt.Sprintf("%s %s..%s", o.Event.Summary, o.Start.Format(time.DateOnly), last.Format(time.DateOnly))
+		}
+		return fmt.Sprintf("%s %s", o.Event.Summary, o.Start.Format(time.DateOnly))
+	}
+	return fmt.Sprintf("%s %s..%s", o.Event.Summary, o.Start.Format("2006-01-02 15:04"), o.End.Format("15:04"))
+}
+
+/**
+ * Whether the event matches the summary and category, when given.
+ */
+func (m *CalendarMatch) Matches(e *CalendarEvent) bool {
+	if m.Summary != "" && !strings.Contains(strings.ToLower(e.Summary), strings.ToLower(m.Summary)) {
+		return false
+	}
+	if m.Category != "" && !slices.ContainsFunc(e.Categories, func(category string) bool {
+		return strings.EqualFold(strings.TrimSpace(category), m.Category)
+	}) {
+		return false
+	}
+	return true
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (e *CalendarEvent) endOf(start time.Time) time.Time {
+	if e.AllDay {
+		return start.AddDate(0, 0, e.Days)
+	}
+	return start.Add(e.Duration)
+}
+
+/**
+ * The starts of all occurrences before the given time, in order.
+ */
+func (e *CalendarEvent) startsUntil(until time.Time) []time.Time {
+	starts := make([]time.Time, 0)
+	if e.rule == nil {
+		if e.Start.Before(until) {
+			starts = append(starts, e.Start)
+		}
+	} else {
+		starts = e.rule.expand(e.Start, until)
+	}
+
+	for _, rdate := range e.rDates {
+		if rdate.Before(until) && !slices.ContainsFunc(starts, rdate.Equal) {
+			starts = append(starts, rdate)
+		}
+	}
+	starts = slices.DeleteFunc(starts, func(start time.Time) bool {
+		return slices.ContainsFunc(e.exDates, start.Equal)
+	})
+
+	slices.SortFunc(starts, time.Time.Compare)
+	return starts
+}
+
+/**
+ * The starts of the recurrences of an event that begins at dtstart,
+ * up to (excluding) the given time.
+ */
+func (r *recurrenceRule) expand(dtstart, until time.Time) []time.Time {
+	starts := make([]time.Time, 0)
+	count := 0
+	for period := 0; period < icsMaxPeriods; period++ {
+		candidates, periodStart := r.candidates(dtstart, period)
+		if !periodStart.Before(until) || (!r.until.IsZero() && periodStart.After(r.until)) {
+			break
+		}
+
+		for _, start := range candidates {
+			if start.Before(dtstart) {
+				continue
+			}
+			if (!r.until.IsZero() && start.After(r.until)) || (r.count > 0 && count >= r.count) {
+				return starts
+			}
+			count++ // COUNT includes the excluded ones
+			if start.Before(until) {
+				starts = append(starts, start)
+			}
+		}
+	}
+	return starts
+}
+
+/**
+ * The sorted occurrences within the n-th period (year, month, week or
+ * day) of the rule, and when that period begins.
+ */
+func (r *recurrenceRule) candidates(dtstart time.Time, n int) ([]time.Time, time.Time) {
+	loc := dtstart.Location()
+	hh, mm, ss := dtstart.Clock()
+	at := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, hh, mm, ss, 0, loc) }
+
+	days := make([]time.Time, 0)
+	var periodStart time.Time
+	switch r.freq {
+	case "YEARLY":
+		y := dtstart.Year() + n*r.interval
+		periodStart = time.Date(y, time.January, 1, 0, 0, 0, 0, loc)
+		switch {
+		case len(r.byMonth) == 0 && len(r.byMonthDay) == 0 && len(r.byDay) > 0:
+			days = r.weekdaysIn(at(y, time.January, 1), at(y+1, time.January, 1))
+		case len(r.byMonth) == 0 && len(r.byMonthDay) == 0:
+			days = r.monthDays(y, dtstart.Month(), dtstart.Day(), at)
+		default:
+			for m := time.January; m <= time.December; m++ {
+				if len(r.byMonth) == 0 || slices.Contains(r.byMonth, int(m)) {
+					days = append(days, r.monthDays(y, m, dtstart.Day(), at)...)
+				}
+			}
+		}
+
+	case "MONTHLY":
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(n*r.interval), 1, 0, 0, 0, 0, loc)
+		periodStart = first
+		if len(r.byMonth) == 0 || slices.Contains(r.byMonth, int(first.Month())) {
+			days = r.monthDays(first.Year(), first.Month(), dtstart.Day(), at)
+		}
+
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.weekStart) + 7) % 7
+		y, m, d := dtstart.Date()
+		weekStart := at(y, m, d-offset+7*n*r.interval)
+		periodStart = time.Date(weekStart.Year(), weekStart.Month(), weekStart.Day(), 0, 0, 0, 0, loc)
+		weekdays := []time.Weekday{dtstart.Weekday()}
+		if len(r.byDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, wd := range r.byDay {
+				weekdays = append(weekdays, wd.weekday)
+			}
+		}
+		for _, wd := range weekdays {
+			day := weekStart.AddDate(0, 0, (int(wd)-int(r.weekStart)+7)%7)
+			if len(r.byMonth) == 0 || slices.Contains(r.byMonth, int(day.Month())) {
+				days = append(days, day)
+			}
+		}
+
+	default: // DAILY
+		y, m, d := dtstart.Date()
+		day := at(y, m, d+n*r.interval)
+		periodStart = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc)
+		if r.allowsDay(day) {
+			days = append(days, day)
+		}
+	}
+
+	slices.SortFunc(days, time.Time.Compare)
+	days = slices.CompactFunc(days, time.Time.Equal)
+	return r.setPositions(days), periodStart
+}
+
+/**
+ * The days of a month selected by BYMONTHDAY and/or BYDAY (ordinals
+ * relative to the month), else the day of the month of DTSTART.
+ */
+func (r *recurrenceRule) monthDays(y int, m time.Month, defaultDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	daysInMonth := time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		if defaultDay > daysInMonth {
+			return nil // i.e. the 31st in April is skipped
+		}
+		return []time.Time{at(y, m, defaultDay)}
+	}
+
+	var days []time.Time
+	if len(r.byDay) > 0 {
+		days = r.weekdaysIn(at(y, m, 1), at(y, m+1, 1))
+	} else {
+		for d := 1; d <= daysInMonth; d++ {
+			days = append(days, at(y, m, d))
+		}
+	}
+
+	if len(r.byMonthDay) > 0 {
+		days = slices.DeleteFunc(days, func(day time.Time) bool {
+			return !r.matchesMonthDay(day.Day(), daysInMonth)
+		})
+	}
+	return days
+}
+
+/**
+ * The days from..until (excluded) that are among BYDAY, ordinals
+ * counted within that span.
+ */
+func (r *recurrenceRule) weekdaysIn(from, until time.Time) []time.Time {
+	days := make([]time.Time, 0)
+	for _, wd := range r.byDay {
+		matching := make([]time.Time, 0)
+		for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == wd.weekday {
+				matching = append(matching, day)
+			}
+		}
+
+		switch {
+		case wd.ordinal == 0:
+			days = append(days, matching...)
+		case wd.ordinal > 0 && wd.ordinal <= len(matching):
+			days = append(days, matching[wd.ordinal-1])
+		case wd.ordinal < 0 && -wd.ordinal <= len(matching):
+			days = append(days, matching[len(matching)+wd.ordinal])
+		}
+	}
+	return days
+}
+
+/**
+ * BY* parts limit the days of a DAILY rule.
+ */
+func (r *recurrenceRule) allowsDay(day time.Time) bool {
+	if len(r.byMonth) > 0 && !slices.Contains(r.byMonth, int(day.Month())) {
+		return false
+	}
+	if len(r.byMonthDay) > 0 {
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		if !r.matchesMonthDay(day.Day(), daysInMonth) {
+			return false
+		}
+	}
+	if len(r.byDay) > 0 {
+		return slices.ContainsFunc(r.byDay, func(wd weekdayNum) bool { return wd.weekday == day.Weekday() })
+	}
+	return true
+}
+
+func (r *recurrenceRule) matchesMonthDay(day, daysInMonth int) bool {
+	for _, md := range r.byMonthDay {
+		if md == day || (md < 0 && daysInMonth+1+md == day) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Keep only the BYSETPOS positions (1-based, negative from the end).
+ */
+func (r *recurrenceRule) setPositions(days []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 {
+		return days
+	}
+
+	selected := make([]time.Time, 0, len(r.bySetPos))
+	for _, pos := range r.bySetPos {
+		if pos > 0 && pos <= len(days) {
+			selected = append(selected, days[pos-1])
+		} else if pos < 0 && -pos <= len(days) {
+			selected = append(selected, days[len(days)+pos])
+		}
+	}
+	slices.SortFunc(selected, time.Time.Compare)
+	return slices.CompactFunc(selected, time.Time.Equal)
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The events (VEVENT) of an iCalendar stream. Instances overriding
+ * those of a recurring event (RECURRENCE-ID) replace them.
+ * @returns the events and the reasons why some were skipped
+ */
+func ParseICS(r io.Reader) ([]*CalendarEvent, []string, error) {
+	properties, err := readICSProperties(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	events := make([]*CalendarEvent, 0)
+	skipped := make([]string, 0)
+	var current *CalendarEvent
+	var problem error
+	depth := 0 // components nested in the event, i.e. VALARM
+	for _, prop := range properties {
+		switch {
+		case prop.name == "BEGIN" && prop.value == "VEVENT":
+			current, problem = &CalendarEvent{}, nil
+		case current == nil:
+		case prop.name == "BEGIN":
+			depth++
+		case prop.name == "END" && depth > 0:
+			depth--
+		case depth > 0:
+		case prop.name == "END" && prop.value == "VEVENT":
+			if problem == nil {
+				problem = current.finish()
+			}
+			if problem != nil {
+				skipped = append(skipped, fmt.Sprintf("%q %s", current.Summary, problem))
+			} else {
+				events = append(events, current)
+			}
+			current = nil
+		default:
+			if err := current.setProperty(prop); err != nil && problem == nil {
+				problem = err
+			}
+		}
+	}
+
+	// overridden instances are no longer part of their series
+	for _, event := range events {
+		if event.recurrence.IsZero() {
+			continue
+		}
+		for _, master := range events {
+			if master.UID == event.UID && master.recurrence.IsZero() {
+				master.exDates = append(master.exDates, event.recurrence)
+			}
+		}
+	}
+	return events, skipped, nil
+}
+
+func (e *CalendarEvent) setProperty(prop icsProperty) error {
+	var err error
+	switch prop.name {
+	case "UID":
+		e.UID = prop.value
+	case "SUMMARY":
+		e.Summary = icsText(prop.value)
+	case "CATEGORIES":
+		for _, category := range splitICSList(prop.value) {
+			e.Categories = append(e.Categories, icsText(category))
+		}
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseICSTime(prop)
+	case "DTEND":
+		e.end, _, err = parseICSTime(prop)
+	case "DURATION":
+		e.length, err = parseICSDuration(prop.value)
+	case "RRULE":
+		e.rruleText = prop.value
+	case "EXDATE", "RDATE":
+		for _, value := range strings.Split(prop.value, ",") {
+			at, _, err := parseICSTime(icsProperty{prop.name, prop.params, value})
+			if err != nil {
+				return err
+			}
+			if prop.name == "EXDATE" {
+				e.exDates = append(e.exDates, at)
+			} else {
+				e.rDates = append(e.rDates, at)
+			}
+		}
+	case "RECURRENCE-ID":
+		e.recurrence, _, err = parseICSTime(prop)
+	}
+	return err
+}
+
+/**
+ * Resolve what depends on DTSTART, properties come in any order.
+ */
+func (e *CalendarEvent) finish() error {
+	if e.Start.IsZero() {
+		return fmt.Errorf("no DTSTART")
+	}
+
+	switch {
+	case !e.end.IsZero() && e.AllDay:
+		e.Days = max(1, int(e.end.Sub(e.Start).Round(24*time.Hour)/(24*time.Hour)))
+	case !e.end.IsZero():
+		e.Duration = max(0, e.end.Sub(e.Start))
+	case e.AllDay:
+		e.Days = max(1, int(e.length/(24*time.Hour)))
+	default:
+		e.Duration = max(0, e.length)
+	}
+
+	if e.rruleText != "" {
+		rule, err := parseRRule(e.rruleText, e.Start.Location())
+		if err != nil {
+			return err
+		}
+		e.rule = rule
+	}
+	return nil
+}
+
+/**
+ * Read content lines, unfolding continuation lines.
+ */
+func readICSProperties(r io.Reader) ([]icsProperty, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	properties := make([]icsProperty, 0, len(lines))
+	for _, line := range lines {
+		if prop, ok := parseICSLine(line); ok {
+			properties = append(properties, prop)
+		}
+	}
+	return properties, nil
+}
+
+/**
+ * NAME;PARAM=VALUE;...:VALUE where parameter values may be quoted.
+ */
+func parseICSLine(line string) (icsProperty, bool) {
+	quoted, colon := false, -1
+	for idx, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = idx
+			break
+		}
+	}
+	if colon == -1 {
+		return icsProperty{}, false
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	prop := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if key, value, ok := strings.Cut(param, "="); ok {
+			prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+	return prop, true
+}
+
+/**
+ * A DATE (all-day, local) or DATE-TIME in UTC, in the zone of its TZID
+ * or else floating (local).
+ */
+func parseICSTime(prop icsProperty) (time.Time, bool, error) {
+	value := strings.TrimSpace(prop.value)
+	loc := time.Local
+	if tzid, ok := prop.params["TZID"]; ok {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+
+	if prop.params["VALUE"] == "DATE" || len(value) == len(ICS_DATE_FORMAT) {
+		at, err := time.ParseInLocation(ICS_DATE_FORMAT, value, loc)
+		return at, true, err
+	}
+
+	if utc, ok := strings.CutSuffix(value, "Z"); ok {
+		at, err := time.ParseInLocation(ICS_DATETIME_FORMAT, utc, time.UTC)
+		return at, false, err
+	}
+	at, err := time.ParseInLocation(ICS_DATETIME_FORMAT, value, loc)
+	return at, false, err
+}
+
+/**
+ * [+|-]P[nW][nD][T[nH][nM][nS]]
+ */
+func parseICSDuration(value string) (time.Duration, error) {
+	rest := strings.TrimPrefix(strings.TrimPrefix(value, "+"), "-")
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour, 'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+	var total time.Duration
+	number := ""
+	for idx := 0; idx < len(rest); idx++ {
+		c := rest[idx]
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+		case c == 'T':
+		case units[c] != 0 && number != "":
+			n, _ := strconv.Atoi(number)
+			total += time.Duration(n) * units[c]
+			number = ""
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+
+	if strings.HasPrefix(value, "-") {
+		total = -total
+	}
+	return total, nil
+}
+
+/**
+ * FREQ=...;INTERVAL=...;COUNT=...|UNTIL=...;BYMONTH;BYMONTHDAY;BYDAY;
+ * BYSETPOS;WKST. Rules by hour, minute, week or year day aren't
+ * supported.
+ */
+func parseRRule(value string, loc *time.Location) (*recurrenceRule, error) {
+	rule := &recurrenceRule{interval: 1, weekStart: time.Monday}
+	for _, part := range strings.Split(value, ";") {
+		key, val, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(val)
+			if !slices.Contains([]string{"YEARLY", "MONTHLY", "WEEKLY", "DAILY"}, rule.freq) {
+				return nil, fmt.Errorf("unsupported FREQ=%s", val)
+			}
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(val)
+			if err == nil && rule.interval < 1 {
+				err = fmt.Errorf("INTERVAL must be positive")
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			var allDay bool
+			rule.until, allDay, err = parseICSTime(icsProperty{"UNTIL", map[string]string{}, val})
+			if err == nil && allDay { // inclusive
+				rule.until = time.Date(rule.until.Year(), rule.until.Month(), rule.until.Day(), 23, 59, 59, 0, loc)
+			}
+		case "BYMONTH":
+			rule.byMonth, err = parseICSInts(val, 1, 12)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseICSInts(val, -31, 31)
+		case "BYSETPOS":
+			rule.bySetPos, err = parseICSInts(val, -366, 366)
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				wd, ok := icsWeekdays[strings.ToUpper(day[max(0, len(day)-2):])]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY=%s", val)
+				}
+				ordinal := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if ordinal, err = strconv.Atoi(strings.TrimPrefix(prefix, "+")); err != nil {
+						return nil, fmt.Errorf("invalid BYDAY=%s", val)
+					}
+				}
+				rule.byDay = append(rule.byDay, weekdayNum{ordinal, wd})
+			}
+		case "WKST":
+			wd, ok := icsWeekdays[strings.ToUpper(val)]
+			if !ok {
+				err = fmt.Errorf("invalid WKST=%s", val)
+			}
+			rule.weekStart = wd
+		default:
+			return nil, fmt.Errorf("unsupported %s in RRULE", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.freq == "" {
+		return nil, fmt.Errorf("RRULE without FREQ")
+	}
+	return rule, nil
+}
+
+func parseICSInts(value string, lowest, highest int) ([]int, error) {
+	numbers := make([]int, 0)
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimPrefix(item, "+"))
+		if err != nil || n == 0 || n < lowest || n > highest {
+			return nil, fmt.Errorf("invalid number %q", item)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
+
+/**
+ * Split a list on commas that aren't escaped.
+ */
+func splitICSList(value string) []string {
+	items := make([]string, 0)
+	start := 0
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] == '\\' {
+			idx++
+		} else if value[idx] == ',' {
+			items = append(items, value[start:idx])
+			start = idx + 1
+		}
+	}
+	return append(items, value[start:])
+}
+
+func icsText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(strings.TrimSpace(value))
+}
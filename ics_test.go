@@ -0,0 +1,234 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Recurrence expansion, mostly the examples of RFC 5545 3.8.5.3
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The events of a calendar made of the given VEVENT lines.
+ */
+func parseTestEvents(t *testing.T, lines ...string) ([]*CalendarEvent, []string) {
+	t.Helper()
+	ics := "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" + strings.Join(lines, "\r\n") + "\r\nEND:VCALENDAR\r\n"
+	events, skipped, err := ParseICS(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("ParseICS() error = %v", err)
+	}
+	return events, skipped
+}
+
+/**
+ * The starts of the occurrences of the single event in the lines,
+ * within the given years.
+ */
+func occurrenceStarts(t *testing.T, fromYear, untilYear int, layout string, lines ...string) []string {
+	t.Helper()
+	events, skipped := parseTestEvents(t, lines...)
+	if len(events) != 1 || len(skipped) != 0 {
+		t.Fatalf("got %d events, skipped %v; want 1 event", len(events), skipped)
+	}
+
+	from := time.Date(fromYear, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := time.Date(untilYear+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	starts := make([]string, 0)
+	for _, occurrence := range events[0].Between(from, until) {
+		starts = append(starts, occurrence.Start.Format(layout))
+	}
+	return starts
+}
+
+/* ----------------------------------------------------------------
+ *					T e s t s
+ *-----------------------------------------------------------------*/
+
+func TestRecurrenceExpansion(t *testing.T) {
+	tests := []struct {
+		name    string
+		dtstart string
+		rrule   string
+		want    []string // dates, at the time of DTSTART
+	}{
+		{"daily for 10 occurrences", "19970902T090000Z", "FREQ=DAILY;COUNT=10", []string{
+			"1997-09-02", "1997-09-03", "1997-09-04", "1997-09-05", "1997-09-06",
+			"1997-09-07", "1997-09-08", "1997-09-09", "1997-09-10", "1997-09-11",
+		}},
+		{"every other day until", "19970902T090000Z", "FREQ=DAILY;INTERVAL=2;UNTIL=19970912T000000Z", []string{
+			"1997-09-02", "1997-09-04", "1997-09-06", "1997-09-08", "1997-09-10",
+		}},
+		{"every 10 days, 5 occurrences", "19970902T090000Z", "FREQ=DAILY;INTERVAL=10;COUNT=5", []string{
+			"1997-09-02", "1997-09-12", "1997-09-22", "1997-10-02", "1997-10-12",
+		}},
+		{"daily in January", "19980101T090000Z", "FREQ=DAILY;UNTIL=19980105T090000Z;BYMONTH=1", []string{
+			"1998-01-01", "1998-01-02", "1998-01-03", "1998-01-04", "1998-01-05",
+		}},
+		{"weekly for 10 occurrences", "19970902T090000Z", "FREQ=WEEKLY;COUNT=10", []string{
+			"1997-09-02", "1997-09-09", "1997-09-16", "1997-09-23", "1997-09-30",
+			"1997-10-07", "1997-10-14", "1997-10-21", "1997-10-28", "1997-11-04",
+		}},
+		{"weekly on Tuesday and Thursday for five weeks", "19970902T090000Z", "FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH", []string{
+			"1997-09-02", "1997-09-04", "1997-09-09", "1997-09-11", "1997-09-16",
+			"1997-09-18", "1997-09-23", "1997-09-25", "1997-09-30", "1997-10-02",
+		}},
+		{"every other week on Monday, Wednesday and Friday", "19970901T090000Z", "FREQ=WEEKLY;INTERVAL=2;UNTIL=19971224T000000Z;WKST=SU;BYDAY=MO,WE,FR;COUNT=9", []string{
+			"1997-09-01", "1997-09-03", "1997-09-05", "1997-09-15", "1997-09-17",
+			"1997-09-19", "1997-09-29", "1997-10-01", "1997-10-03",
+		}},
+		{"week start matters", "19970805T090000Z", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU", []string{
+			"1997-08-05", "1997-08-17", "1997-08-19", "1997-08-31",
+		}},
+		{"week start matters, Monday", "19970805T090000Z", "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO", []string{
+			"1997-08-05", "1997-08-10", "1997-08-19", "1997-08-24",
+		}},
+		{"monthly on the first Friday", "19970905T090000Z", "FREQ=MONTHLY;COUNT=10;BYDAY=1FR", []string{
+			"1997-09-05", "1997-10-03", "1997-11-07", "1997-12-05", "1998-01-02",
+			"1998-02-06", "1998-03-06", "1998-04-03", "1998-05-01", "1998-06-05",
+		}},
+		{"every other month on the first and last Sunday", "19970907T090000Z", "FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU", []string{
+			"1997-09-07", "1997-09-28", "1997-11-02", "1997-11-30", "1998-01-04",
+			"1998-01-25", "1998-03-01", "1998-03-29", "1998-05-03", "1998-05-31",
+		}},
+		{"monthly on the second-to-last Monday", "19970922T090000Z", "FREQ=MONTHLY;COUNT=6;BYDAY=-2MO", []string{
+			"1997-09-22", "1997-10-20", "1997-11-17", "1997-12-22", "1998-01-19", "1998-02-16",
+		}},
+		{"monthly on the third-to-last day", "19970928T090000Z", "FREQ=MONTHLY;BYMONTHDAY=-3;COUNT=6", []string{
+			"1997-09-28", "1997-10-29", "1997-11-28", "1997-12-29", "1998-01-29", "1998-02-26",
+		}},
+		{"monthly on the 2nd and 15th", "19970902T090000Z", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=2,15", []string{
+			"1997-09-02", "1997-09-15", "1997-10-02", "1997-10-15", "1997-11-02",
+			"1997-11-15", "1997-12-02", "1997-12-15", "1998-01-02", "1998-01-15",
+		}},
+		{"monthly on the first and last day", "19970930T090000Z", "FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1", []string{
+			"1997-09-30", "1997-10-01", "1997-10-31", "1997-11-01", "1997-11-30",
+			"1997-12-01", "1997-12-31", "1998-01-01", "1998-01-31", "1998-02-01",
+		}},
+		{"Friday the 13th", "19970902T090000Z", "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13;COUNT=5", []string{
+			"1998-02-13", "1998-03-13", "1998-11-13", "1999-08-13", "2000-10-13",
+		}},
+		{"the 31st skips shorter months", "20250131T090000Z", "FREQ=MONTHLY;COUNT=4", []string{
+			"2025-01-31", "2025-03-31", "2025-05-31", "2025-07-31",
+		}},
+		{"last work day of the month", "19970930T090000Z", "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=6", []string{
+			"1997-09-30", "1997-10-31", "1997-11-28", "1997-12-31", "1998-01-30", "1998-02-27",
+		}},
+		{"third Tuesday, Wednesday or Thursday", "19970904T090000Z", "FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3", []string{
+			"1997-09-04", "1997-10-07", "1997-11-06",
+		}},
+		{"yearly in June and July", "19970610T090000Z", "FREQ=YEARLY;COUNT=10;BYMONTH=6,7", []string{
+			"1997-06-10", "1997-07-10", "1998-06-10", "1998-07-10", "1999-06-10",
+			"1999-07-10", "2000-06-10", "2000-07-10", "2001-06-10", "2001-07-10",
+		}},
+		{"every other year in January to March", "19970310T090000Z", "FREQ=YEARLY;INTERVAL=2;COUNT=10;BYMONTH=1,2,3", []string{
+			"1997-03-10", "1999-01-10", "1999-02-10", "1999-03-10", "2001-01-10",
+			"2001-02-10", "2001-03-10", "2003-01-10", "2003-02-10", "2003-03-10",
+		}},
+		{"Thanksgiving", "20241128T090000Z", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH;COUNT=3", []string{
+			"2024-11-28", "2025-11-27", "2026-11-26",
+		}},
+		{"every 20th Monday of the year", "19970519T090000Z", "FREQ=YEARLY;BYDAY=20MO;COUNT=3", []string{
+			"1997-05-19", "1998-05-18", "1999-05-17",
+		}},
+		{"leap day only in leap years", "20240229T090000Z", "FREQ=YEARLY;COUNT=3", []string{
+			"2024-02-29", "2028-02-29", "2032-02-29",
+		}},
+		{"US presidential election day", "19961105T090000Z", "FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8;COUNT=3", []string{
+			"1996-11-05", "2000-11-07", "2004-11-02",
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := occurrenceStarts(t, 1990, 2040, "2006-01-02 15:04",
+				"BEGIN:VEVENT", "UID:test", "SUMMARY:Test",
+				"DTSTART:"+test.dtstart, "DURATION:PT1H", "RRULE:"+test.rrule,
+				"END:VEVENT")
+
+			want := make([]string, len(test.want))
+			for idx, day := range test.want {
+				want[idx] = day + " 09:00"
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("RRULE:%s\n got %v\nwant %v", test.rrule, got, want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceExceptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"EXDATE still counts", []string{
+			"DTSTART:20250101T080000Z", "RRULE:FREQ=DAILY;COUNT=4", "EXDATE:20250102T080000Z,20250104T080000Z",
+		}, []string{"2025-01-01 08:00", "2025-01-03 08:00"}},
+		{"RDATE adds", []string{
+			"DTSTART:20250101T080000Z", "RRULE:FREQ=WEEKLY;COUNT=2", "RDATE:20250103T080000Z",
+		}, []string{"2025-01-01 08:00", "2025-01-03 08:00", "2025-01-08 08:00"}},
+		{"UNTIL on a date is inclusive", []string{
+			"DTSTART;VALUE=DATE:20251224", "RRULE:FREQ=DAILY;UNTIL=20251226",
+		}, []string{"2025-12-24 00:00", "2025-12-25 00:00", "2025-12-26 00:00"}},
+		{"in the zone of its TZID", []string{
+			"DTSTART;TZID=Europe/Madrid:20250324T090000", "RRULE:FREQ=WEEKLY;COUNT=2",
+		}, []string{"2025-03-24 09:00", "2025-03-31 09:00"}}, // across the DST change
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lines := slices.Concat([]string{"BEGIN:VEVENT", "UID:test", "SUMMARY:Test"}, test.lines, []string{"END:VEVENT"})
+			if got := occurrenceStarts(t, 2025, 2026, "2006-01-02 15:04", lines...); !slices.Equal(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRecurrenceOverride(t *testing.T) {
+	events, _ := parseTestEvents(t,
+		"BEGIN:VEVENT", "UID:standup", "SUMMARY:Standup", "DTSTART:20250106T090000Z",
+		"DURATION:PT15M", "RRULE:FREQ=DAILY;COUNT=3", "END:VEVENT",
+		"BEGIN:VEVENT", "UID:standup", "SUMMARY:Standup (moved)", "RECURRENCE-ID:20250107T090000Z",
+		"DTSTART:20250107T140000Z", "DURATION:PT15M", "END:VEVENT")
+
+	from := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	until := from.AddDate(0, 1, 0)
+	got := make([]string, 0)
+	for _, event := range events {
+		for _, occurrence := range event.Between(from, until) {
+			got = append(got, occurrence.String())
+		}
+	}
+	slices.Sort(got)
+
+	want := []string{
+		"Standup (moved) 2025-01-07 14:00..14:15",
+		"Standup 2025-01-06 09:00..09:15",
+		"Standup 2025-01-08 09:00..09:15",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestRecurrenceUnsupported(t *testing.T) {
+	for _, rrule := range []string{"FREQ=HOURLY", "FREQ=DAILY;BYHOUR=9", "INTERVAL=2", "FREQ=WEEKLY;BYDAY=XX"} {
+		events, skipped := parseTestEvents(t, "BEGIN:VEVENT", "SUMMARY:Odd", "DTSTART:20250101T090000Z", "RRULE:"+rrule, "END:VEVENT")
+		if len(events) != 0 || len(skipped) != 1 {
+			t.Errorf("RRULE:%s gave %d events, skipped %v; want it skipped", rrule, len(events), skipped)
+		}
+	}
+}
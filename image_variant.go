@@ -0,0 +1,243 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Wallpaper variants: dark ones for bright wallpapers, upright photos.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"lordofscripts/carousel/app"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	CACHE_GROUP          = "goCarousel"
+	CACHE_DARK_DIR       = "dark"
+	CACHE_UPRIGHT_DIR    = "upright"
+	VARIANT_JPEG_QUALITY = 90
+	LUMINANCE_SAMPLES    = 64 // sample grid is NxN
+)
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the color scheme name reported by a session handler is
+ * a dark one, i.e. "prefer-dark", "Adwaita-dark".
+ */
+func isDarkScheme(scheme string) bool {
+	return strings.Contains(strings.ToLower(scheme), "dark")
+}
+
+/**
+ * Get (and create) a subdirectory of our per-user cache.
+ */
+func getAppCacheDir(subdir string) (string, error) {
+	dir := path.Join(app.GetUserCacheDir(), CACHE_GROUP, subdir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+func decodeImageFile(filename string) (image.Image, error) {
+	fd, err := openWallpaper(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	img, _, err := image.Decode(fd)
+	return img, err
+}
+
+/**
+ * Mean luminance (0..1) of the image computed over a sample grid
+ * rather than every pixel; wallpapers are large.
+ */
+func meanLuminance(img image.Image) float64 {
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/LUMINANCE_SAMPLES, 1)
+	stepY := max(bounds.Dy()/LUMINANCE_SAMPLES, 1)
+
+	var sum float64
+	var count int
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / 0xffff
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+/**
+ * Whether the image's mean luminance exceeds the threshold.
+ */
+func isBrightImage(filename string, threshold float64) (bool, error) {
+	img, err := decodeImageFile(filename)
+	if err != nil {
+		return false, err
+	}
+
+	return meanLuminance(img) > threshold, nil
+}
+
+/**
+ * Get the dark variant of a wallpaper, synthesizing it if it isn't
+ * cached yet.
+ * @returns (string) full path of the (cached) dark variant
+ */
+func getDarkVariant(filename string, opts DarkVariantOpts) (string, error) {
+	return getCachedVariant(filename, CACHE_DARK_DIR, darkVariantParams(opts), func(img image.Image) image.Image {
+		return darken(img, opts.Dim, opts.Desaturate)
+	})
+}
+
+/**
+ * The dark variant of a wallpaper if it is cached already, which also
+ * tells it was found bright enough under the same threshold.
+ */
+func lookupDarkVariant(filename string, opts DarkVariantOpts) (string, bool) {
+	variant, err := variantPath(filename, CACHE_DARK_DIR, darkVariantParams(opts))
+	if err != nil || !FileExists(variant) {
+		return "", false
+	}
+	return variant, true
+}
+
+func darkVariantParams(opts DarkVariantOpts) string {
+	return fmt.Sprintf("%.3f|%.3f|%.3f", opts.Dim, opts.Desaturate, opts.Threshold)
+}
+
+/**
+ * Get the upright variant of a photo whose EXIF orientation says it
+ * is rotated or mirrored.
+ */
+func getUprightVariant(filename string, orientation int) (string, error) {
+	return getCachedVariant(filename, CACHE_UPRIGHT_DIR, fmt.Sprint(orientation), func(img image.Image) image.Image {
+		return orient(img, orientation)
+	})
+}
+
+/**
+ * Get a variant of an image from the cache, synthesizing it with
+ * transform if it isn't cached yet. The cache key covers the source
+ * file identity (path, size, modification time) and the variant
+ * parameters so that any change to either produces a fresh variant.
+ */
+func getCachedVariant(filename, subdir, params string, transform func(image.Image) image.Image) (string, error) {
+	variant, err := variantPath(filename, subdir, params)
+	if err != nil {
+		return "", err
+	}
+	if FileExists(variant) {
+		return variant, nil
+	}
+
+	img, err := decodeImageFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	// written aside so that an interrupted encode is never served
+	fdOut, err := os.Create(variant + ".tmp")
+	if err != nil {
+		return "", err
+	}
+	err = jpeg.Encode(fdOut, transform(img), &jpeg.Options{Quality: VARIANT_JPEG_QUALITY})
+	if cerr := fdOut.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(variant + ".tmp")
+		return "", err
+	}
+
+	return variant, os.Rename(variant+".tmp", variant)
+}
+
+/**
+ * Where the variant of an image is cached.
+ */
+func variantPath(filename, subdir, params string) (string, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+
+	cacheDir, err := getAppCacheDir(subdir)
+	if err != nil {
+		return "", err
+	}
+
+	absName, _ := filepath.Abs(filename)
+	key := fmt.Sprintf("%s|%d|%d|%s", absName, fi.Size(), fi.ModTime().UnixNano(), params)
+	sum := md5.Sum([]byte(key))
+	return path.Join(cacheDir, hex.EncodeToString(sum[:])+".jpg"), nil
+}
+
+/**
+ * Dim and desaturate an image.
+ * @param dim (float64) 0..1 brightness reduction
+ * @param desaturate (float64) 0..1 blend towards the gray level
+ */
+func darken(img image.Image, dim, desaturate float64) *image.RGBA {
+	dim = clamp01(dim)
+	desaturate = clamp01(desaturate)
+
+	bounds := img.Bounds()
+	out := image.NewRGBA(bounds)
+	draw.Draw(out, bounds, img, bounds.Min, draw.Src)
+
+	keep := 1.0 - dim
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := out.RGBAAt(x, y)
+			r, g, b := float64(c.R), float64(c.G), float64(c.B)
+			gray := 0.2126*r + 0.7152*g + 0.0722*b
+			r = (r + (gray-r)*desaturate) * keep
+			g = (g + (gray-g)*desaturate) * keep
+			b = (b + (gray-b)*desaturate) * keep
+			out.SetRGBA(x, y, color.RGBA{uint8(r), uint8(g), uint8(b), c.A})
+		}
+	}
+
+	return out
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
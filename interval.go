@@ -0,0 +1,184 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Interval schedules, due some time after the wallpaper last changed
+ * rather than at set times.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	EVERY_MIN_INTERVAL = time.Minute // we only ever check once a minute
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Read again when the wallpaper & each schedule last changed, those
+ * interval schedules are measured from. Unreadable state is logged
+ * and taken as never, so that they are due right away.
+ */
+func (e *ScheduleEnv) Reload() {
+	e.LastChange, e.LastRuns = time.Time{}, make(map[string]time.Time)
+
+	if status, err := GetWallpaperStatus(); err == nil {
+		e.LastChange = status.TimeStamp
+	} else if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("wallpaper status: %s", err)
+	}
+
+	store, err := NewStateStore()
+	if err != nil {
+		log.Printf("state store: %s", err)
+		return
+	}
+	for title, record := range store.Schedules {
+		if !record.LastRun.IsZero() {
+			e.LastRuns[title] = record.LastRun
+		}
+	}
+}
+
+/**
+ * Note a run of the schedule, for environments that don't reload
+ * what was persisted, i.e. simulations.
+ */
+func (e *ScheduleEnv) MarkRun(s *Schedule, at time.Time) {
+	if e.LastRuns == nil {
+		e.LastRuns = make(map[string]time.Time)
+	}
+	e.LastRuns[s.Title] = at
+	if s.Command.ChangesWallpaper() && s.Target.HasDesktop() {
+		e.LastChange = at
+	}
+}
+
+func (s *Schedule) IsInterval() bool {
+	return s.Every != ""
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The interval of the schedule and the most its jitter may add.
+ */
+func (s *Schedule) interval() (every, jitter time.Duration, err error) {
+	if every, err = time.ParseDuration(s.Every); err != nil {
+		return 0, 0, fmt.Errorf("every must be a duration like 15m or 2h30m, not %q", s.Every)
+	}
+	if every < EVERY_MIN_INTERVAL {
+		return 0, 0, fmt.Errorf("every must be at least %s, not %s", EVERY_MIN_INTERVAL, s.Every)
+	}
+
+	if s.Jitter != "" {
+		if jitter, err = time.ParseDuration(s.Jitter); err != nil || jitter < 0 {
+			return 0, 0, fmt.Errorf("jitter must be a duration like 5m, not %q", s.Jitter)
+		}
+	}
+	return every, jitter, nil
+}
+
+/**
+ * When the schedule last changed something: the wallpaper it changes,
+ * by whoever, or else its own last run.
+ */
+func (s *Schedule) lastChange(env *ScheduleEnv) time.Time {
+	last := env.LastRuns[s.Title]
+	if s.Command.ChangesWallpaper() && s.Target.HasDesktop() && env.LastChange.After(last) {
+		last = env.LastChange
+	}
+	return last
+}
+
+/**
+ * The minute the interval is up, the zero time if it never ran. The
+ * jitter is drawn from the last change so that it stays put for every
+ * check until the next one.
+ */
+func (s *Schedule) intervalDue(env *ScheduleEnv) (time.Time, error) {
+	every, jitter, err := s.interval()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	last := s.lastChange(env)
+	if last.IsZero() {
+		return last, nil
+	}
+	last = last.Truncate(time.Minute) // jobs run a few seconds into their minute
+
+	if jitter > 0 {
+		hash := fnv.New64a()
+		fmt.Fprintf(hash, "%s@%d", s.Title, last.Unix())
+		every += time.Duration(hash.Sum64() % uint64(jitter+1))
+	}
+	due := last.Add(every)
+	if rounded := due.Truncate(time.Minute); rounded.Before(due) {
+		due = rounded.Add(time.Minute)
+	}
+	return due, nil
+}
+
+/**
+ * Whether the interval is up in the minute of the given time, and it
+ * is one the cron expression (if any) allows.
+ */
+func (s *Schedule) isIntervalUp(now time.Time, env *ScheduleEnv) (bool, error) {
+	due, err := s.intervalDue(env)
+	if err != nil || due.After(now) {
+		return false, err
+	}
+	return s.cronAllows(now)
+}
+
+/**
+ * The first minute allowed by the cron expression (if any) once the
+ * interval is up. Runs in between are not foreseen: the interval is
+ * measured from the last change known to the environment.
+ */
+func (s *Schedule) nextInterval(now time.Time, env *ScheduleEnv, allowCurrent bool) (time.Time, error) {
+	due, err := s.intervalDue(env)
+	if err != nil {
+		return due, err
+	}
+
+	from, allow := now, allowCurrent
+	if due.After(now) {
+		from, allow = due, true
+	}
+	if s.CronTab == "" {
+		from = from.Truncate(time.Minute)
+		if !allow {
+			from = from.Add(time.Minute)
+		}
+		return from, nil
+	}
+	return gronx.NextTickAfter(s.CronTab, from, allow)
+}
+
+func (s *Schedule) cronAllows(now time.Time) (bool, error) {
+	if s.CronTab == "" {
+		return true, nil
+	}
+	return gronx.New().IsDue(s.CronTab, now.Truncate(time.Minute))
+}
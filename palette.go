@@ -0,0 +1,309 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Wallpaper color palette extraction (median cut).
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	PALETTE_FILE    = "palette.json"
+	PALETTE_SAMPLES = 128 // sample grid is NxN
+	PALETTE_COLORS  = 6
+)
+
+// Gnome (libadwaita) only accepts these named accent colors, in
+// order of preference among equally near ones
+var gnomeAccentColors = []struct {
+	name  string
+	color color.RGBA
+}{
+	{"blue", color.RGBA{0x35, 0x84, 0xe4, 0xff}},
+	{"teal", color.RGBA{0x21, 0x90, 0xa4, 0xff}},
+	{"green", color.RGBA{0x3a, 0x94, 0x4a, 0xff}},
+	{"yellow", color.RGBA{0xc8, 0x88, 0x00, 0xff}},
+	{"orange", color.RGBA{0xed, 0x5b, 0x00, 0xff}},
+	{"red", color.RGBA{0xe6, 0x2d, 0x42, 0xff}},
+	{"pink", color.RGBA{0xd5, 0x61, 0x99, 0xff}},
+	{"purple", color.RGBA{0x91, 0x41, 0xac, 0xff}},
+	{"slate", color.RGBA{0x6f, 0x83, 0x96, 0xff}},
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type PaletteColor struct {
+	Hex    string  `json:"hex"`
+	Red    uint8   `json:"r"`
+	Green  uint8   `json:"g"`
+	Blue   uint8   `json:"b"`
+	Weight float64 `json:"weight"` // fraction of sampled pixels
+}
+
+/**
+ * The palette of a wallpaper. Colors are sorted by weight, the
+ * first one being the dominant color.
+ */
+type Palette struct {
+	Source    string         `json:"source"`
+	TimeStamp time.Time      `json:"timestamp"`
+	Dominant  string         `json:"dominant"`
+	Colors    []PaletteColor `json:"colors"`
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type colorBox []color.RGBA
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Extract the palette of an image file using the median cut
+ * algorithm over a sample grid of its pixels.
+ * @param filename (string) image file (SVG is not supported)
+ * @param count (int) number of colors to extract
+ */
+func NewPaletteFromFile(filename string, count int) (*Palette, error) {
+	img, err := decodeImageFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if count < 1 {
+		count = PALETTE_COLORS
+	}
+
+	pixels := samplePixels(img)
+	if len(pixels) == 0 {
+		return nil, fmt.Errorf("no pixels in %s", filename)
+	}
+
+	boxes := medianCut(colorBox(pixels), count)
+	colors := make([]PaletteColor, 0, len(boxes))
+	seen := make(map[string]int)
+	for _, box := range boxes {
+		avg := box.average()
+		weight := float64(len(box)) / float64(len(pixels))
+		hex := toHexColor(avg)
+		if idx, exists := seen[hex]; exists {
+			colors[idx].Weight += weight
+			continue
+		}
+
+		seen[hex] = len(colors)
+		colors = append(colors, PaletteColor{
+			Hex:    hex,
+			Red:    avg.R,
+			Green:  avg.G,
+			Blue:   avg.B,
+			Weight: weight,
+		})
+	}
+
+	sort.SliceStable(colors, func(i, j int) bool { return colors[i].Weight > colors[j].Weight })
+
+	return &Palette{
+		Source:    filename,
+		TimeStamp: time.Now(),
+		Dominant:  colors[0].Hex,
+		Colors:    colors,
+	}, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (p *Palette) DominantColor() color.RGBA {
+	c := p.Colors[0]
+	return color.RGBA{c.Red, c.Green, c.Blue, 0xff}
+}
+
+/**
+ * Save the palette as JSON so that other tools (terminal, status bar)
+ * can pick it up, never half-written.
+ */
+func (p *Palette) Save(filename string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(filename+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The channel (0=R 1=G 2=B) with the widest range in the box
+ */
+func (b colorBox) widestChannel() (int, uint8) {
+	var lo = [3]uint8{255, 255, 255}
+	var hi [3]uint8
+	for _, c := range b {
+		for ch, v := range [3]uint8{c.R, c.G, c.B} {
+			lo[ch] = min(lo[ch], v)
+			hi[ch] = max(hi[ch], v)
+		}
+	}
+
+	channel := 0
+	for ch := 1; ch < 3; ch++ {
+		if hi[ch]-lo[ch] > hi[channel]-lo[channel] {
+			channel = ch
+		}
+	}
+
+	return channel, hi[channel] - lo[channel]
+}
+
+func (b colorBox) average() color.RGBA {
+	var r, g, bl int
+	for _, c := range b {
+		r += int(c.R)
+		g += int(c.G)
+		bl += int(c.B)
+	}
+
+	n := len(b)
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 0xff}
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The stable location of the palette of the current wallpaper.
+ */
+func GetPaletteFile() (string, error) {
+	dir, err := getAppCacheDir("")
+	if err != nil {
+		return "", err
+	}
+
+	return path.Join(dir, PALETTE_FILE), nil
+}
+
+/**
+ * The Gnome accent color name closest to the given color.
+ */
+func nearestGnomeAccent(c color.RGBA) string {
+	best := "slate"
+	bestDistance := math.MaxFloat64
+	for _, accent := range gnomeAccentColors {
+		dr := float64(c.R) - float64(accent.color.R)
+		dg := float64(c.G) - float64(accent.color.G)
+		db := float64(c.B) - float64(accent.color.B)
+		// weighted euclidean, the "redmean" approximation of perceived distance
+		rmean := (float64(c.R) + float64(accent.color.R)) / 2
+		distance := (2+rmean/256)*dr*dr + 4*dg*dg + (2+(255-rmean)/256)*db*db
+		if distance < bestDistance {
+			best, bestDistance = accent.name, distance
+		}
+	}
+
+	return best
+}
+
+func toHexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func samplePixels(img image.Image) []color.RGBA {
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/PALETTE_SAMPLES, 1)
+	stepY := max(bounds.Dy()/PALETTE_SAMPLES, 1)
+
+	pixels := make([]color.RGBA, 0, PALETTE_SAMPLES*PALETTE_SAMPLES)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r, g, b, _ := img.At(x, y).RGBA()
+			pixels = append(pixels, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 0xff})
+		}
+	}
+
+	return pixels
+}
+
+/**
+ * Split the color space into at most count boxes, always cutting the
+ * box with the widest channel range at its median.
+ */
+func medianCut(pixels colorBox, count int) []colorBox {
+	boxes := []colorBox{pixels}
+	for len(boxes) < count {
+		target := -1
+		var widest uint8
+		var channel int
+		for idx, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if ch, spread := box.widestChannel(); spread > widest || target == -1 {
+				target, widest, channel = idx, spread, ch
+			}
+		}
+
+		if target == -1 || widest == 0 {
+			break
+		}
+
+		box := boxes[target]
+		sort.Slice(box, func(i, j int) bool {
+			return channelOf(box[i], channel) < channelOf(box[j], channel)
+		})
+		// never split a run of equal values across two boxes
+		median := len(box) / 2
+		pivot := channelOf(box[median], channel)
+		for median > 0 && channelOf(box[median-1], channel) == pivot {
+			median--
+		}
+		if median == 0 {
+			for median < len(box) && channelOf(box[median], channel) == pivot {
+				median++
+			}
+		}
+		boxes[target] = box[:median]
+		boxes = append(boxes, box[median:])
+	}
+
+	return boxes
+}
+
+func channelOf(c color.RGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	}
+	return c.B
+}
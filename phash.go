@@ -0,0 +1,189 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Perceptual image hashes to find the same picture at different
+ * sizes, qualities and names.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	HASH_AVERAGE    = "ahash"
+	HASH_DIFFERENCE = "dhash"
+	HASH_PERCEPTUAL = "phash"
+
+	HASH_BITS          = 64
+	DEFAULT_HASH_LIMIT = 8 // max. differing bits of near-duplicates
+	phashSize          = 32
+	hashSide           = 8
+	cellSamples        = 4 // samples per cell side when scaling down
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+type ImageHashes struct {
+	Average    uint64 `json:"ahash"`
+	Difference uint64 `json:"dhash"`
+	Perceptual uint64 `json:"phash"`
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Number of differing bits (0..64) between two images by the given
+ * algorithm: ahash, dhash or phash (the default).
+ */
+func (h ImageHashes) Distance(other ImageHashes, algorithm string) int {
+	switch algorithm {
+	case HASH_AVERAGE:
+		return bits.OnesCount64(h.Average ^ other.Average)
+	case HASH_DIFFERENCE:
+		return bits.OnesCount64(h.Difference ^ other.Difference)
+	default:
+		return bits.OnesCount64(h.Perceptual ^ other.Perceptual)
+	}
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+func NewImageHashes(img image.Image) ImageHashes {
+	return ImageHashes{
+		Average:    averageHash(img),
+		Difference: differenceHash(img),
+		Perceptual: perceptualHash(img),
+	}
+}
+
+/**
+ * Whether the algorithm name is one we know, empty means the default.
+ */
+func IsHashAlgorithm(algorithm string) bool {
+	return algorithm == "" || algorithm == HASH_AVERAGE || algorithm == HASH_DIFFERENCE || algorithm == HASH_PERCEPTUAL
+}
+
+/**
+ * 8x8 grayscale thumbnail, one bit per pixel brighter than the mean.
+ */
+func averageHash(img image.Image) uint64 {
+	gray := grayThumbnail(img, hashSide, hashSide)
+	var mean float64
+	for _, v := range gray {
+		mean += v
+	}
+	mean /= float64(len(gray))
+
+	var hash uint64
+	for idx, v := range gray {
+		if v > mean {
+			hash |= 1 << idx
+		}
+	}
+	return hash
+}
+
+/**
+ * 9x8 grayscale thumbnail, one bit per pixel brighter than its right
+ * neighbour.
+ */
+func differenceHash(img image.Image) uint64 {
+	gray := grayThumbnail(img, hashSide+1, hashSide)
+	var hash uint64
+	for y := range hashSide {
+		for x := range hashSide {
+			row := y * (hashSide + 1)
+			if gray[row+x] > gray[row+x+1] {
+				hash |= 1 << (y*hashSide + x)
+			}
+		}
+	}
+	return hash
+}
+
+/**
+ * Lowest 8x8 frequencies of the DCT of a 32x32 grayscale thumbnail,
+ * one bit per coefficient above their median (the DC term excluded).
+ */
+func perceptualHash(img image.Image) uint64 {
+	gray := grayThumbnail(img, phashSize, phashSize)
+
+	// separable 2D DCT-II, only the low frequencies are needed
+	rows := make([]float64, phashSize*hashSide)
+	for y := range phashSize {
+		for u := range hashSide {
+			rows[y*hashSide+u] = dct(func(x int) float64 { return gray[y*phashSize+x] }, u)
+		}
+	}
+
+	coeffs := make([]float64, hashSide*hashSide)
+	for v := range hashSide {
+		for u := range hashSide {
+			coeffs[v*hashSide+u] = dct(func(y int) float64 { return rows[y*hashSide+u] }, v)
+		}
+	}
+
+	sorted := slices.Clone(coeffs[1:])
+	slices.Sort(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for idx, c := range coeffs {
+		if idx > 0 && c > median {
+			hash |= 1 << idx
+		}
+	}
+	return hash
+}
+
+/**
+ * Coefficient k of the (unnormalized) DCT-II of phashSize values.
+ */
+func dct(value func(int) float64, k int) float64 {
+	var sum float64
+	for n := range phashSize {
+		sum += value(n) * math.Cos(math.Pi/phashSize*(float64(n)+0.5)*float64(k))
+	}
+	return sum
+}
+
+/**
+ * Luminance (0..1) of an image scaled down to w x h. Each cell is the
+ * mean of a few samples rather than of all its pixels; wallpapers are
+ * large.
+ */
+func grayThumbnail(img image.Image, w, h int) []float64 {
+	bounds := img.Bounds()
+	gray := make([]float64, w*h)
+	for y := range h {
+		for x := range w {
+			var sum float64
+			for sy := range cellSamples {
+				for sx := range cellSamples {
+					px := bounds.Min.X + ((x*cellSamples+sx)*bounds.Dx()+bounds.Dx()/2)/(w*cellSamples)
+					py := bounds.Min.Y + ((y*cellSamples+sy)*bounds.Dy()+bounds.Dy()/2)/(h*cellSamples)
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / 0xffff
+				}
+			}
+			gray[y*w+x] = sum / (cellSamples * cellSamples)
+		}
+	}
+	return gray
+}
@@ -0,0 +1,87 @@
+//go:build unix
+
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Unix-specific system probe, read from sysfs.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SYSFS_POWER_SUPPLY = "/sys/class/power_supply"
+	SYSFS_DRM          = "/sys/class/drm"
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * On battery when no mains adapter is online and some battery is
+ * discharging. Machines without power supply info are deemed on AC.
+ */
+func (p SystemProbe) OnBattery() (bool, error) {
+	supplies, err := os.ReadDir(SYSFS_POWER_SUPPLY)
+	if err != nil {
+		return false, nil
+	}
+
+	discharging := false
+	for _, supply := range supplies {
+		dir := filepath.Join(SYSFS_POWER_SUPPLY, supply.Name())
+		switch readSysfs(dir, "type") {
+		case "Mains", "USB":
+			if readSysfs(dir, "online") == "1" {
+				return false, nil
+			}
+		case "Battery":
+			if readSysfs(dir, "scope") != "Device" { // not a mouse or such
+				discharging = discharging || readSysfs(dir, "status") == "Discharging"
+			}
+		}
+	}
+	return discharging, nil
+}
+
+/**
+ * The connectors of all graphic cards with a monitor attached,
+ * whatever the display server.
+ */
+func (p SystemProbe) Monitors() (int, error) {
+	connectors, err := filepath.Glob(filepath.Join(SYSFS_DRM, "card*-*", "status"))
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, status := range connectors {
+		if readSysfs(filepath.Dir(status), "status") == "connected" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+func readSysfs(dir, attribute string) string {
+	data, err := os.ReadFile(filepath.Join(dir, attribute))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,68 @@
+//go:build windows
+
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Windows-specific system probe.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll") // user32 is in session_windoze.go
+
+	procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+	procGetSystemMetrics     = user32.NewProc("GetSystemMetrics")
+)
+
+const (
+	SM_CMONITORS    = 80
+	AC_LINE_ONLINE  = 1
+	AC_LINE_UNKNOWN = 255
+)
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * On battery when the AC line is offline. Unknown counts as AC.
+ */
+func (p SystemProbe) OnBattery() (bool, error) {
+	var status systemPowerStatus
+	if ok, _, err := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return false, err
+	}
+	return status.ACLineStatus != AC_LINE_ONLINE && status.ACLineStatus != AC_LINE_UNKNOWN, nil
+}
+
+/**
+ * The monitors of the desktop.
+ */
+func (p SystemProbe) Monitors() (int, error) {
+	count, _, _ := procGetSystemMetrics.Call(SM_CMONITORS)
+	return int(count), nil
+}
@@ -17,6 +17,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
@@ -153,15 +154,48 @@ func ExecuteCommand(cmd ScheduleAction, settings *Settings) error {
 	return Execute(cmd.Command, cmd.Argument, settings)
 }
 
+/**
+ * Execute the action of a schedule entry on its behalf. While the
+ * carousel is locked those changing the wallpaper are held back with
+ * a warning, locking & unlocking it is always done.
+ */
+func ExecuteJob(job Schedule, settings *Settings) error {
+	if job.Command.ChangesWallpaper() && IsLocked(settings) {
+		return NewWarningMsg(WarnCarouselLocked, "carousel is locked")
+	}
+	if job.Target == TargetBoth && job.LockArgument != "" {
+		// desktop & lock screen each from its own source
+		if err := execute(job.Command, job.Argument, job.Title, TargetDesktop, settings); err != nil {
+			return err
+		}
+		return execute(job.Command, job.LockArgument, job.Title, TargetLockScreen, settings)
+	}
+
+	return execute(job.Command, job.Argument, job.Title, job.Target, settings)
+}
+
 /**
  * Execute the application sub-command
  */
 func Execute(command Action, argument string, settings *Settings) error {
+	return execute(command, argument, "", TargetDesktop, settings)
+}
+
+/**
+ * Execute the application sub-command on the desktop, the lock screen
+ * or both.
+ */
+func ExecuteOn(target WallpaperTarget, command Action, argument string, settings *Settings) error {
+	return execute(command, argument, "", target, settings)
+}
+
+func execute(command Action, argument, scheduleTitle string, target WallpaperTarget, settings *Settings) error {
 	var err error = nil
 	wm := NewWallpaperMgr(settings)
 	if err = wm.Init(); err != nil {
 		return err
 	}
+	wm.WithSchedule(scheduleTitle).WithTarget(target)
 
 	switch command {
 	case ActIdentify:
@@ -188,12 +222,49 @@ func Execute(command Action, argument string, settings *Settings) error {
 	case ActChosenCarousel:
 		wm.SetWallpaperFromCarousel(argument)
 
+	case ActPreviousWallpaper:
+		err = wm.SetPreviousWallpaper()
+
+	case ActNextWallpaper:
+		err = wm.SetNextWallpaper()
+
+	case ActUndoWallpaper:
+		err = wm.UndoWallpaper()
+
+	case ActFavorite, ActUnfavorite:
+		err = wm.FavoriteCurrent(command == ActFavorite)
+
+	case ActRateWallpaper:
+		var rating int
+		if rating, err = strconv.Atoi(argument); err == nil {
+			err = wm.RateCurrent(rating)
+		}
+
+	case ActBanWallpaper:
+		err = wm.BanCurrent()
+
+	case ActTagWallpaper, ActUntagWallpaper:
+		err = wm.TagCurrent(strings.Split(argument, ","), command == ActTagWallpaper)
+
+	case ActReindex:
+		var stats CatalogStats
+		stats, err = wm.Reindex()
+		fmt.Println("Catalog:", stats)
+
+	case ActFindDuplicates:
+		var groups [][]CatalogEntry
+		if groups, err = wm.FindDuplicates(); err == nil {
+			PrintDuplicates(groups)
+		}
+
 	case ActStatus:
 		if IsLocked(settings) {
 			fmt.Println("Carousel is Locked")
 		} else {
 			fmt.Println("Carousel is NOT locked")
 		}
+		PrintWallpaperStatus()
+		PrintCatalogStats()
 
 	case ActNone:
 
@@ -24,9 +24,11 @@ import (
 
 const (
 	// Unix specific
-	EXT_LSUSB    = "/usr/bin/lsusb"    // @note from JSON config
-	EXT_LSBLK    = "/usr/bin/lsblk"    // @note idem
-	EXT_LOGINCTL = "/usr/bin/loginctl" // @note idem
+	EXT_LSUSB     = "/usr/bin/lsusb"    // @note from JSON config
+	EXT_LSBLK     = "/usr/bin/lsblk"    // @note idem
+	EXT_LOGINCTL  = "/usr/bin/loginctl" // @note idem
+	EXT_SHELL     = "/bin/sh"           // runs user hooks
+	EXT_SHELL_CMD = "-c"
 )
 
 /* ----------------------------------------------------------------
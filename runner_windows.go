@@ -42,6 +42,11 @@ var guidUsbClass = windows.GUID{
 	Data4: [8]byte{0x80, 0x56, 0x44, 0x45, 0x53, 0x54, 0x00, 0x00},
 }
 
+const (
+	EXT_SHELL     = "cmd.exe" // runs user hooks
+	EXT_SHELL_CMD = "/C"
+)
+
 const (
 	DIGCF_PRESENT       = 0x00000002
 	SPDRP_HARDWAREID    = 0x00000001
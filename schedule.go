@@ -0,0 +1,397 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * When schedules are due: cron expressions & solar triggers, and the
+ * conditions that may hold them back.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adhocore/gronx"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SOLAR_SEARCH_DAYS = 366 // how far ahead we look for the next solar trigger
+	SEARCH_HORIZON    = 366 * 24 * time.Hour
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * What schedules are evaluated against besides the time.
+ */
+type ScheduleEnv struct {
+	Location *GeoLocation
+	Calendar *Calendar    // nil without calendars in the options
+	Probe    ISystemProbe // state of the system for the conditions
+
+	LastChange time.Time            // of the desktop wallpaper, by anyone
+	LastRuns   map[string]time.Time // of the schedules, by title
+	options    *Options
+	titles     map[string]int // how many recorded schedules go by each
+}
+
+/**
+ * A solar event shifted by an offset, i.e. sunrise+00:30
+ */
+type SolarTrigger struct {
+	Event  string
+	Offset time.Duration
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) The environment of the schedules in the settings. When some
+ * calendar can't be read the error is returned along with what could
+ * be loaded.
+ */
+func NewScheduleEnv(settings *Settings) (*ScheduleEnv, error) {
+	env := &ScheduleEnv{Location: settings.UserOptions.Location, Probe: SystemProbe{}}
+	env.options, env.titles = &settings.UserOptions, make(map[string]int)
+	for idx := range settings.Schedules {
+		if job := &settings.Schedules[idx]; job.isRecorded(env.options) {
+			env.titles[job.Title]++
+		}
+	}
+	env.Reload()
+	if len(settings.UserOptions.Calendars) == 0 {
+		return env, nil
+	}
+
+	var err error
+	env.Calendar, err = NewCalendar(settings.UserOptions.Calendars)
+	return env, err
+}
+
+/**
+ * (Ctor) Parse EVENT[(+|-)HH:MM], i.e. civil_dusk or sunset-01:15
+ */
+func ParseSolarTrigger(spec string) (SolarTrigger, error) {
+	spec = strings.TrimSpace(spec)
+	event, offset, sign := spec, "", time.Duration(1)
+	if at := strings.IndexAny(spec, "+-"); at != -1 {
+		event, offset = spec[:at], spec[at+1:]
+		if spec[at] == '-' {
+			sign = -1
+		}
+	}
+
+	trigger := SolarTrigger{Event: event}
+	if !IsSolarEvent(event) {
+		return trigger, fmt.Errorf("unknown solar event %q", event)
+	}
+
+	if offset != "" {
+		var hours, minutes int
+		if n, err := fmt.Sscanf(offset, "%d:%d", &hours, &minutes); err != nil || n != 2 || hours < 0 || minutes < 0 || minutes > 59 {
+			return trigger, fmt.Errorf("solar offset must be HH:MM, not %q", offset)
+		}
+		trigger.Offset = sign * (time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+	}
+	return trigger, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * When the trigger fires on the given day (to the minute).
+ * @returns (bool) false if the event doesn't take place that day
+ */
+func (t SolarTrigger) On(day time.Time, where *GeoLocation) (time.Time, bool) {
+	at, ok := where.EventOn(t.Event, day)
+	if !ok {
+		return at, false
+	}
+	return at.Add(t.Offset).Truncate(time.Minute), true
+}
+
+func (t SolarTrigger) String() string {
+	if t.Offset == 0 {
+		return t.Event
+	}
+
+	sign, offset := "+", t.Offset
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	return fmt.Sprintf("%s%s%02d:%02d", t.Event, sign, int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+/**
+ * A schedule is triggered either by its cron expression, by a solar
+ * event or by an interval since the last change; in the latter cases a
+ * cron expression, if any, only tells on which days (day of month,
+ * month & day of week) or in which minutes respectively. A calendar condition
+ * holds it back outside matching events, an active window outside
+ * its days and the system conditions while they don't hold. The runs
+ * of interval & catching up schedules are remembered by their title,
+ * so theirs must be unique.
+ */
+func (s *Schedule) Validate(env *ScheduleEnv) error {
+	if env.options != nil && s.isRecorded(env.options) {
+		if strings.TrimSpace(s.Title) == "" {
+			return fmt.Errorf("interval & catching up schedules need a title")
+		}
+		if env.titles[s.Title] > 1 {
+			return fmt.Errorf("title %q is used by another interval or catching up schedule", s.Title)
+		}
+	}
+	if !s.Target.IsValid() {
+		return fmt.Errorf("unknown target %q", s.Target)
+	}
+
+	if s.Calendar != nil {
+		if env.Calendar == nil {
+			return fmt.Errorf("calendar condition needs options.calendars")
+		}
+		if s.Calendar.Summary == "" && s.Calendar.Category == "" {
+			return fmt.Errorf("calendar condition needs a summary or category")
+		}
+	}
+
+	if s.HasWindow() {
+		if _, err := NewScheduleWindow(s, env.Location); err != nil {
+			return err
+		}
+	}
+	if s.When != nil {
+		if err := s.When.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := ValidateCatchUpPolicy(s.CatchUp); err != nil {
+		return err
+	}
+
+	if s.IsInterval() {
+		if s.Solar != "" {
+			return fmt.Errorf("every and solar can't go together")
+		}
+		if _, _, err := s.interval(); err != nil {
+			return err
+		}
+		if s.CronTab != "" && !gronx.IsValid(s.CronTab) {
+			return fmt.Errorf("invalid cron expression %q", s.CronTab)
+		}
+		return nil
+	}
+	if s.Jitter != "" {
+		return fmt.Errorf("jitter needs every")
+	}
+
+	if s.Solar == "" {
+		if !gronx.IsValid(s.CronTab) {
+			return fmt.Errorf("invalid cron expression %q", s.CronTab)
+		}
+		return nil
+	}
+
+	if _, err := ParseSolarTrigger(s.Solar); err != nil {
+		return err
+	}
+	if env.Location == nil {
+		return fmt.Errorf("solar trigger %s needs options.location", s.Solar)
+	}
+	if s.CronTab != "" && !gronx.IsValid(s.dayFilter()) {
+		return fmt.Errorf("invalid cron expression %q", s.CronTab)
+	}
+	return nil
+}
+
+/**
+ * Whether the schedule is due in the minute of the given time.
+ */
+func (s *Schedule) IsDue(now time.Time, env *ScheduleEnv) (bool, error) {
+	if s.HasWindow() {
+		window, err := NewScheduleWindow(s, env.Location)
+		if err != nil || !window.Contains(now) {
+			return false, err
+		}
+	}
+
+	due, err := s.isTriggered(now, env)
+	if !due || err != nil {
+		return false, err
+	}
+
+	if s.Calendar != nil {
+		minute := now.Truncate(time.Minute)
+		if len(env.Calendar.Matching(s.Calendar, minute, minute.Add(time.Minute))) == 0 {
+			return false, nil
+		}
+	}
+
+	if s.When != nil { // last, probing the system is the dearest
+		return s.When.Hold(env.Probe)
+	}
+	return true, nil
+}
+
+/**
+ * When the schedule is next due. System conditions can't be foreseen
+ * and are left out.
+ * @param allowCurrent (bool) whether the current minute counts
+ */
+func (s *Schedule) NextTick(now time.Time, env *ScheduleEnv, allowCurrent bool) (time.Time, error) {
+	if s.Calendar == nil {
+		return s.nextActive(now, env, allowCurrent)
+	}
+
+	// the first trigger during a matching event
+	for _, event := range env.Calendar.Matching(s.Calendar, now, now.Add(SEARCH_HORIZON)) {
+		from, allow := now, allowCurrent
+		if event.Start.After(now) { // cron is in our time zone, not the event's
+			from, allow = event.Start.In(now.Location()), true
+		}
+
+		tick, err := s.nextActive(from, env, allow)
+		if err == nil && event.Overlaps(tick, tick.Add(time.Minute)) {
+			return tick, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no matching calendar event within a year")
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the cron expression, solar trigger or interval fires in the
+ * minute of the given time.
+ */
+func (s *Schedule) isTriggered(now time.Time, env *ScheduleEnv) (bool, error) {
+	if s.IsInterval() {
+		return s.isIntervalUp(now, env)
+	}
+	if s.Solar == "" { // gronx wants second 0, we may run a bit later
+		return gronx.New().IsDue(s.CronTab, now.Truncate(time.Minute))
+	}
+
+	trigger, err := s.solarTrigger(env.Location)
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Truncate(time.Minute)
+	for delta := -1; delta <= 1; delta++ { // offsets may cross midnight
+		day := now.AddDate(0, 0, delta)
+		if at, ok := trigger.On(day, env.Location); ok && at.Equal(minute) {
+			return s.onDay(day), nil
+		}
+	}
+	return false, nil
+}
+
+/**
+ * The next trigger on a day of the active window, if any.
+ */
+func (s *Schedule) nextActive(now time.Time, env *ScheduleEnv, allowCurrent bool) (time.Time, error) {
+	if !s.HasWindow() {
+		return s.nextTrigger(now, env, allowCurrent)
+	}
+
+	window, err := NewScheduleWindow(s, env.Location)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	from, allow := now, allowCurrent
+	if !window.Contains(from) {
+		if from, err = s.windowOpen(window, now); err != nil {
+			return from, err
+		}
+		allow = true
+	}
+	horizon := from.Add(SEARCH_HORIZON)
+	for {
+		tick, err := s.nextTrigger(from, env, allow)
+		if err != nil || window.Contains(tick) {
+			return tick, err
+		}
+		if from, err = s.windowOpen(window, tick); err != nil {
+			return from, err
+		}
+		if from.After(horizon) {
+			return time.Time{}, fmt.Errorf("not due within a year of its active window")
+		}
+		allow = true
+	}
+}
+
+func (s *Schedule) nextTrigger(now time.Time, env *ScheduleEnv, allowCurrent bool) (time.Time, error) {
+	if s.IsInterval() {
+		return s.nextInterval(now, env, allowCurrent)
+	}
+	if s.Solar == "" {
+		return gronx.NextTickAfter(s.CronTab, now, allowCurrent)
+	}
+
+	trigger, err := s.solarTrigger(env.Location)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	minute := now.Truncate(time.Minute)
+	for delta := -1; delta <= SOLAR_SEARCH_DAYS; delta++ {
+		day := now.AddDate(0, 0, delta)
+		at, ok := trigger.On(day, env.Location)
+		if !ok || at.Before(minute) || (at.Equal(minute) && !allowCurrent) {
+			continue
+		}
+		if s.onDay(day) {
+			return at, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s doesn't happen within a year", s.Solar)
+}
+
+func (s *Schedule) solarTrigger(where *GeoLocation) (SolarTrigger, error) {
+	if where == nil {
+		return SolarTrigger{}, fmt.Errorf("solar trigger %s needs options.location", s.Solar)
+	}
+	return ParseSolarTrigger(s.Solar)
+}
+
+/**
+ * Whether the days of the cron expression (if any) include this one.
+ */
+func (s *Schedule) onDay(day time.Time) bool {
+	if s.CronTab == "" {
+		return true
+	}
+
+	y, m, d := day.Date()
+	due, err := gronx.New().IsDue(s.dayFilter(), time.Date(y, m, d, 0, 0, 0, 0, day.Location()))
+	return err == nil && due
+}
+
+/**
+ * The cron expression with its minute & hour replaced so that it is
+ * due at midnight of the days it names.
+ */
+func (s *Schedule) dayFilter() string {
+	fields := strings.Fields(s.CronTab)
+	if len(fields) != 5 {
+		return s.CronTab
+	}
+	fields[0], fields[1] = "0", "0"
+	return strings.Join(fields, " ")
+}
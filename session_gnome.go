@@ -13,6 +13,7 @@ package carousel
 
 import (
 	"fmt"
+	"image/color"
 	"log"
 	"strings"
 
@@ -33,12 +34,16 @@ const (
 	orgCinnamonBackground = "org.cinnamon.desktop.background"
 	orgGnomeScheme        = "org.gnome.desktop.interface"
 	orgCinnamonScheme     = "org.cinnamon.desktop.interface"
+	orgGnomeScreensaver   = "org.gnome.desktop.screensaver"
 )
 
 /* ----------------------------------------------------------------
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
 var _ ISessionManager = (*GnomeSession)(nil)
+var _ IAccentColorManager = (*GnomeSession)(nil)
+var _ ILockScreenManager = (*GnomeSession)(nil)
+var _ ISlideshowManager = (*GnomeSession)(nil)
 
 /* ----------------------------------------------------------------
  *				I n i t i a l i z e r
@@ -166,6 +171,66 @@ func (s *GnomeSession) SetWallpaperLight(filename string) error {
 	return err
 }
 
+/**
+ * Gnome 47+ has a fixed set of named accent colors, the closest one
+ * to the given color is used. Cinnamon has no accent colors.
+ */
+func (s *GnomeSession) SetAccentColor(c color.RGBA) error {
+	if s.schemaInterface == orgCinnamonScheme {
+		return fmt.Errorf("%s has no accent-color", FLAVOR_CINNAMON)
+	}
+
+	// gsettings set org.gnome.desktop.interface accent-color blue
+	_, err := ExecuteProgram(EXT_GSETTINGS,
+		"set",
+		s.schemaInterface,
+		"accent-color",
+		nearestGnomeAccent(c),
+	)
+	return err
+}
+
+func (s *GnomeSession) SetPrimaryColor(c color.RGBA) error {
+	// gsettings set org.gnome.desktop.background primary-color '#rrggbb'
+	_, err := ExecuteProgram(EXT_GSETTINGS,
+		"set",
+		s.schemaBackground,
+		"primary-color",
+		toHexColor(c),
+	)
+	return err
+}
+
+/**
+ * Gnome's lock screen has its own picture. Cinnamon's screensaver
+ * shows the LightDM greeter's background.
+ */
+func (s *GnomeSession) SetLockScreen(filename string) error {
+	if s.schemaBackground == orgCinnamonBackground {
+		return setGreeterBackground(filename)
+	}
+
+	// gsettings set org.gnome.desktop.screensaver picture-uri file://$1
+	_, err := ExecuteProgram(EXT_GSETTINGS,
+		"set",
+		orgGnomeScreensaver,
+		"picture-uri",
+		fmt.Sprintf("file://%s", filename),
+	)
+	return err
+}
+
+/**
+ * Gnome & Cinnamon play background XML slideshows by themselves, in
+ * both Light and Dark mode.
+ */
+func (s *GnomeSession) SetSlideshow(filename string) error {
+	if err := s.SetWallpaperLight(filename); err != nil {
+		return err
+	}
+	return s.SetWallpaperDark(filename)
+}
+
 func (s *GnomeSession) String() string {
 	var identity string = FLAVOR_GNOME
 	if s.schemaBackground == orgCinnamonBackground {
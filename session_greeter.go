@@ -0,0 +1,58 @@
+//go:build unix
+
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							   go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * LightDM greeter (login & light-locker lock screen) background.
+ * The greeters (gtk, slick, unity) show the user's background as
+ * published by AccountsService, which the user may set without
+ * root privileges via the system D-Bus.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"os"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	EXT_DBUS_SEND = "/usr/bin/dbus-send" // @todo get from JSON config
+
+	dbusAccounts        = "org.freedesktop.Accounts"
+	dbusAccountsUser    = "/org/freedesktop/Accounts/User%d"
+	dbusAccountsService = "org.freedesktop.DisplayManager.AccountsService"
+)
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Set the background the LightDM greeter shows for the current user.
+ * That is what XFCE's light-locker, LXDE and Cinnamon show when the
+ * screen is locked.
+ */
+func setGreeterBackground(filename string) error {
+	if !FileExists(EXT_DBUS_SEND) {
+		return fmt.Errorf("missing %s", EXT_DBUS_SEND)
+	}
+
+	_, err := ExecuteProgram(EXT_DBUS_SEND,
+		"--system",
+		"--print-reply",
+		"--dest="+dbusAccounts,
+		fmt.Sprintf(dbusAccountsUser, os.Getuid()),
+		"org.freedesktop.DBus.Properties.Set",
+		"string:"+dbusAccountsService,
+		"string:BackgroundFile",
+		"variant:string:"+filename,
+	)
+
+	return err
+}
@@ -31,6 +31,7 @@ const (
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
 var _ ISessionManager = (*LxdeSession)(nil)
+var _ ILockScreenManager = (*LxdeSession)(nil)
 
 /* ----------------------------------------------------------------
  *				P u b l i c		T y p e s
@@ -129,6 +130,13 @@ func (s *LxdeSession) SetWallpaperLight(filename string) error {
 	return err
 }
 
+/**
+ * LXDE locks the screen with the LightDM greeter (light-locker).
+ */
+func (s *LxdeSession) SetLockScreen(filename string) error {
+	return setGreeterBackground(filename)
+}
+
 func (s *LxdeSession) String() string {
 	return FLAVOR_LXDE
 }
@@ -28,6 +28,7 @@ const (
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
 var _ ISessionManager = (*XfceSession)(nil)
+var _ ILockScreenManager = (*XfceSession)(nil)
 
 /* ----------------------------------------------------------------
  *				P u b l i c		T y p e s
@@ -128,6 +129,13 @@ func (s *XfceSession) SetWallpaperLight(filename string) error {
 	return err
 }
 
+/**
+ * light-locker locks the screen with the LightDM greeter.
+ */
+func (s *XfceSession) SetLockScreen(filename string) error {
+	return setGreeterBackground(filename)
+}
+
 func (s *XfceSession) String() string {
 	return "Xfce4"
 }
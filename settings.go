@@ -9,6 +9,7 @@ package carousel
 
 import (
 	"log"
+	"time"
 
 	"github.com/adhocore/gronx"
 )
@@ -19,8 +20,31 @@ import (
 const (
 	CATEGORY_ICON_FILE = ".category_icon.png"
 	NOTIFIER           = "/usr/bin/notify-send"
+	QUERY_DATE_FORMAT  = "2006-01-02"
 )
 
+// Sensible defaults for the synthesized dark variants. Disabled unless
+// the user opts in via the configuration file.
+var DefaultDarkVariantOpts = DarkVariantOpts{
+	Enabled:    false,
+	Threshold:  0.55,
+	Dim:        0.35,
+	Desaturate: 0.30,
+}
+
+var DefaultDuplicateOpts = DuplicateOpts{
+	Collapse:  false,
+	Algorithm: HASH_PERCEPTUAL,
+	Threshold: DEFAULT_HASH_LIMIT,
+}
+
+var DefaultPaletteOpts = PaletteOpts{
+	Enabled:      false,
+	Colors:       PALETTE_COLORS,
+	SyncAccent:   false,
+	SyncFallback: false,
+}
+
 /* ----------------------------------------------------------------
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
@@ -38,24 +62,148 @@ type Settings struct {
 	KeyDevices       map[string]string             `json:"key_devices"`
 	AngelOptions     AngelOpts                     `json:"angel"`
 	Schedules        []Schedule                    `json:"schedules"`
+	Hooks            HookOpts                      `json:"hooks"`
 }
 
 type Options struct {
-	Notify        bool   `json:"notify"`
-	AssumeSession string `json:"assume_session"`
+	Notify        bool            `json:"notify"`
+	AssumeSession string          `json:"assume_session"`
+	DarkVariant   DarkVariantOpts `json:"dark_variant"`
+	Palette       PaletteOpts     `json:"palette"`
+	HistorySize   int             `json:"history_size"`
+	Duplicates    DuplicateOpts   `json:"duplicates"`
+	Location      *GeoLocation    `json:"location,omitempty"`  // for sun-driven wallpapers
+	Calendars     []string        `json:"calendars,omitempty"` // .ics files for calendar schedules
+	Conflicts     string          `json:"conflicts,omitempty"` // schedules due together: all (default), highest, first
+	CatchUp       string          `json:"catch_up,omitempty"`  // missed schedules: none (default), latest, all
+}
+
+/**
+ * Where on Earth we are, in decimal degrees (north & east positive).
+ */
+type GeoLocation struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+/**
+ * When the desktop is in Dark mode and the chosen wallpaper is bright,
+ * a dimmed/desaturated copy is synthesized and used as the Dark wallpaper
+ * while the original remains the Light one.
+ */
+type DarkVariantOpts struct {
+	Enabled    bool    `json:"enabled"`
+	Threshold  float64 `json:"threshold"`  // mean luminance (0..1) above which an image is bright
+	Dim        float64 `json:"dim"`        // 0..1 brightness reduction
+	Desaturate float64 `json:"desaturate"` // 0..1 color saturation reduction
+}
+
+/**
+ * Palette extraction after every wallpaper change. The palette is saved
+ * as JSON in the cache directory and its dominant color can be pushed
+ * to the desktop's accent and background (primary) colors.
+ */
+type PaletteOpts struct {
+	Enabled      bool `json:"enabled"`
+	Colors       int  `json:"colors"`
+	SyncAccent   bool `json:"sync_accent"`
+	SyncFallback bool `json:"sync_primary_color"`
+}
+
+/**
+ * Near-duplicates are pictures whose perceptual hashes differ in at
+ * most Threshold bits (of 64). When collapsed, each group of them is
+ * a single candidate for selection so that they don't inflate its odds.
+ */
+type DuplicateOpts struct {
+	Collapse  bool   `json:"collapse"`
+	Algorithm string `json:"algorithm"` // ahash, dhash, phash (default)
+	Threshold int    `json:"threshold"`
 }
 
+/**
+ * A category is either a directory (or pack) of wallpapers, a query
+ * (smart category) evaluated against the wallpaper catalog, a feed
+ * of images, a GNOME XML slideshow or a dynamic wallpaper; the latter
+ * two show the picture due at the time.
+ */
 type Category struct {
-	Protected bool   `json:"protected"`
-	KeyName   string `json:"key_name,omitempty"`
-	Directory string `json:"directory"`
+	Protected bool           `json:"protected"`
+	KeyName   string         `json:"key_name,omitempty"`
+	Directory string         `json:"directory,omitempty"`
+	Query     *CategoryQuery `json:"query,omitempty"`
+	Feed      *FeedSource    `json:"feed,omitempty"`
+	Slideshow string         `json:"slideshow,omitempty"` // background XML file
+	Dynamic   string         `json:"dynamic,omitempty"`   // manifest of a dynamic wallpaper
+}
+
+/**
+ * An RSS, Atom or JSON feed of images. Those are downloaded into our
+ * cache which is what the category shows, even when offline.
+ */
+type FeedSource struct {
+	URL        string `json:"url"`
+	Refresh    string `json:"refresh,omitempty"`      // i.e. 6h, defaults to FEED_REFRESH
+	MaxItems   int    `json:"max_items,omitempty"`    // images kept, defaults to FEED_MAX_ITEMS
+	MaxCacheMB int    `json:"max_cache_mb,omitempty"` // defaults to FEED_MAX_CACHE_MB
+}
+
+/**
+ * Smart category: wallpapers in the union of Directories (by default
+ * those of all unprotected categories) whose tags match, i.e.
+ * [autumn, -people] has autumn but no people, and rated at least
+ * MinRating. The date filters apply to the EXIF date the photo was
+ * taken; photos without one never match them.
+ */
+type CategoryQuery struct {
+	Tags        []string `json:"tags,omitempty"`
+	Directories []string `json:"directories,omitempty"`
+	MinRating   int      `json:"min_rating,omitempty"`
+	OnThisDay   bool     `json:"on_this_day,omitempty"` // same day in previous years
+	ThisMonth   bool     `json:"this_month,omitempty"`  // same month of any year
+	TakenFrom   string   `json:"taken_from,omitempty"`  // YYYY-MM-DD inclusive
+	TakenUntil  string   `json:"taken_until,omitempty"` // YYYY-MM-DD inclusive
 }
 
 type Schedule struct {
-	Title    string `json:"title"`
-	Command  Action `json:"action"` // random-in-cat, specific-file,
-	Argument string `json:"argument"`
-	CronTab  string `json:"cron_tab"`
+	Title        string              `json:"title"`
+	Command      Action              `json:"action"` // random-in-cat, specific-file,
+	Argument     string              `json:"argument"`
+	CronTab      string              `json:"cron_tab"`
+	Priority     int                 `json:"priority,omitempty"`      // higher wins conflicts under the highest policy
+	Solar        string              `json:"solar,omitempty"`         // i.e. sunset+00:30, cron_tab then only tells the days
+	Every        string              `json:"every,omitempty"`         // i.e. 15m after the last change, cron_tab then only tells when
+	Jitter       string              `json:"jitter,omitempty"`        // random extra delay of every, up to this
+	Calendar     *CalendarMatch      `json:"calendar,omitempty"`      // only during matching events
+	ActiveFrom   string              `json:"active_from,omitempty"`   // YYYY-MM-DD, inclusive
+	ActiveUntil  string              `json:"active_until,omitempty"`  // YYYY-MM-DD, inclusive
+	Months       []string            `json:"months,omitempty"`        // month names or seasons
+	Yearly       []string            `json:"yearly,omitempty"`        // MM-DD..MM-DD, may wrap the year
+	When         *ScheduleConditions `json:"when,omitempty"`          // state of the system
+	CatchUp      string              `json:"catch_up,omitempty"`      // overrides that of the options
+	Target       WallpaperTarget     `json:"target,omitempty"`        // desktop (default), lockscreen, both
+	LockArgument string              `json:"lock_argument,omitempty"` // lock screen's argument when target is both
+}
+
+/**
+ * Events of the calendars in the options that match both the summary
+ * (a part of it) and the category, when given. Case doesn't matter.
+ */
+type CalendarMatch struct {
+	Summary  string `json:"summary,omitempty"`
+	Category string `json:"category,omitempty"`
+}
+
+/**
+ * Conditions on the state of the system, all those given must hold. A
+ * leading ! negates the hostname, file & env conditions.
+ */
+type ScheduleConditions struct {
+	Power    string `json:"power,omitempty"`    // ac or battery
+	Hostname string `json:"hostname,omitempty"` // shell pattern, i.e. work-*
+	Monitors string `json:"monitors,omitempty"` // connected, i.e. 2 or >=2
+	File     string `json:"file,omitempty"`     // exists, $VARS are expanded
+	Env      string `json:"env,omitempty"`      // NAME is set & not empty, or NAME=value
 }
 
 type AngelOpts struct {
@@ -63,6 +211,16 @@ type AngelOpts struct {
 	LastAction  ScheduleAction `json:"last_action"`
 }
 
+/**
+ * User commands (shell command lines) run before and after every
+ * wallpaper change, i.e. to regenerate terminal or status-bar themes.
+ */
+type HookOpts struct {
+	PreChange  []string `json:"pre_change"`
+	PostChange []string `json:"post_change"`
+	Timeout    string   `json:"timeout,omitempty"` // of each hook, i.e. 10s (HOOK_TIMEOUT)
+}
+
 type ScheduleAction struct {
 	Command  Action `json:"action"` // random-in-cat, specific-file,
 	Argument string `json:"argument"`
@@ -82,11 +240,11 @@ type CategoryCollection = []string
  *				C o n s t r u c t o r s
  *-----------------------------------------------------------------*/
 func NewCategory(dir string) *Category {
-	return &Category{false, "", dir}
+	return &Category{Protected: false, Directory: dir}
 }
 
 func NewCategoryWithProtection(dir string, keyName string) *Category {
-	return &Category{true, keyName, dir}
+	return &Category{Protected: true, KeyName: keyName, Directory: dir}
 }
 
 func NewCategoryCollection(categories ...string) CategoryCollection {
@@ -101,13 +259,88 @@ func NewSchedule(title, cron string, action Action, arg string) *Schedule {
 		return nil
 	}
 
-	return &Schedule{title, action, arg, cron}
+	return &Schedule{Title: title, Command: action, Argument: arg, CronTab: cron}
+}
+
+func NewSmartCategory(tags []string, minRating int) *Category {
+	return &Category{Query: &CategoryQuery{Tags: tags, MinRating: minRating}}
 }
 
 /* ----------------------------------------------------------------
  *				P u b l i c		M e t h o d s
  *-----------------------------------------------------------------*/
 
+func (c *Category) IsSmart() bool {
+	return c.Query != nil
+}
+
+func (c *Category) IsFeed() bool {
+	return c.Feed != nil
+}
+
+func (c *Category) IsSlideshow() bool {
+	return c.Slideshow != ""
+}
+
+func (c *Category) IsDynamic() bool {
+	return c.Dynamic != ""
+}
+
+/**
+ * Whether the query filters by the date the photo was taken.
+ */
+func (q *CategoryQuery) HasDateFilter() bool {
+	return q.OnThisDay || q.ThisMonth || q.TakenFrom != "" || q.TakenUntil != ""
+}
+
+/**
+ * Check the date range is well formed.
+ */
+func (q *CategoryQuery) Validate() error {
+	from, until, err := q.dateRange()
+	if err != nil {
+		return err
+	}
+	if !from.IsZero() && !until.IsZero() && until.Before(from) {
+		return NewAppErrorf(ErrInvalidQuery, "taken_until %s is before taken_from %s", q.TakenUntil, q.TakenFrom)
+	}
+
+	return nil
+}
+
+/**
+ * Whether a photo taken at the given time passes the date filters
+ * as of now.
+ */
+func (q *CategoryQuery) MatchDate(taken, now time.Time) bool {
+	if !q.HasDateFilter() {
+		return true
+	}
+	if taken.IsZero() {
+		return false
+	}
+
+	if q.OnThisDay && (taken.Month() != now.Month() || taken.Day() != now.Day() || taken.Year() >= now.Year()) {
+		return false
+	}
+	if q.ThisMonth && taken.Month() != now.Month() {
+		return false
+	}
+
+	from, until, err := q.dateRange()
+	if err != nil {
+		return false
+	}
+	if !from.IsZero() && taken.Before(from) {
+		return false
+	}
+	if !until.IsZero() && !taken.Before(until.AddDate(0, 0, 1)) {
+		return false
+	}
+
+	return true
+}
+
 /**
  * DESCR
  * @params a (type):
@@ -118,6 +351,24 @@ func NewSchedule(title, cron string, action Action, arg string) *Schedule {
  *				P r i v a t e	M e t h o d s
  *-----------------------------------------------------------------*/
 
+/**
+ * The dates of TakenFrom & TakenUntil (local time), zero if not set.
+ */
+func (q *CategoryQuery) dateRange() (from, until time.Time, err error) {
+	if q.TakenFrom != "" {
+		if from, err = time.ParseInLocation(QUERY_DATE_FORMAT, q.TakenFrom, time.Local); err != nil {
+			return from, until, NewAppErrorf(ErrInvalidQuery, "taken_from: %s", err)
+		}
+	}
+	if q.TakenUntil != "" {
+		if until, err = time.ParseInLocation(QUERY_DATE_FORMAT, q.TakenUntil, time.Local); err != nil {
+			return from, until, NewAppErrorf(ErrInvalidQuery, "taken_until: %s", err)
+		}
+	}
+
+	return from, until, nil
+}
+
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
  *-----------------------------------------------------------------*/
@@ -12,7 +12,7 @@ package carousel
 /* ----------------------------------------------------------------
  *						G l o b a l s
  *-----------------------------------------------------------------*/
-var DefaultUserOptions = Options{Notify: true, AssumeSession: FLAVOR_GNOME}
+var DefaultUserOptions = Options{Notify: true, AssumeSession: FLAVOR_GNOME, DarkVariant: DefaultDarkVariantOpts, Palette: DefaultPaletteOpts, HistorySize: DEFAULT_HISTORY_SIZE, Duplicates: DefaultDuplicateOpts}
 
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
@@ -10,7 +10,7 @@ package carousel
 /* ----------------------------------------------------------------
  *						G l o b a l s
  *-----------------------------------------------------------------*/
-var DefaultUserOptions = Options{Notify: true, AssumeSession: FLAVOR_WINDOWS}
+var DefaultUserOptions = Options{Notify: true, AssumeSession: FLAVOR_WINDOWS, DarkVariant: DefaultDarkVariantOpts, Palette: DefaultPaletteOpts, HistorySize: DEFAULT_HISTORY_SIZE, Duplicates: DefaultDuplicateOpts}
 
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
@@ -0,0 +1,259 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Dry runs of the schedules over a period of time: which job runs
+ * when and what it would put up.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SIMULATE_MAX_STEPS = 100000 // a year of every-minute jobs is way too much to review
+
+	SIM_FORMAT_TEXT = "text"
+	SIM_FORMAT_JSON = "json"
+	SIM_FORMAT_CSV  = "csv"
+
+	// why a step doesn't show what it would pick
+	SIM_NOTE_LOCKED      = "locked"
+	SIM_NOTE_YIELDS      = "yields to a conflicting job"
+	SIM_NOTE_HISTORY     = "from the history"
+	SIM_NOTE_CONDITIONAL = "if its conditions hold"
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * What a due job would do. A job applying different sources to the
+ * desktop & lock screen takes two steps.
+ */
+type SimulationStep struct {
+	Time     time.Time       `json:"time"`
+	Job      int             `json:"job"` // 1-based as in -verify
+	Title    string          `json:"title"`
+	Command  Action          `json:"action"`
+	Argument string          `json:"argument,omitempty"`
+	Target   WallpaperTarget `json:"target,omitempty"`
+	Carousel string          `json:"carousel,omitempty"`
+	Category string          `json:"category,omitempty"`
+	File     string          `json:"file,omitempty"`
+	Locked   bool            `json:"locked"`         // the carousel, after the step
+	Note     string          `json:"note,omitempty"` // i.e. locked
+}
+
+/**
+ * Walks the schedules over a period picking wallpapers as the real
+ * thing would, but reproducibly for a given seed.
+ */
+type Simulator struct {
+	settings *Settings
+	env      *ScheduleEnv
+	manager  *WallpaperManager
+	locked   bool
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) A simulator starting from the current lock state and last
+ * changes. The environment is copied as interval schedules move it on.
+ */
+func NewSimulator(settings *Settings, env *ScheduleEnv, seed uint64) *Simulator {
+	own := *env
+	own.LastRuns = maps.Clone(env.LastRuns)
+	return &Simulator{
+		settings: settings,
+		env:      &own,
+		manager:  NewWallpaperMgr(settings).WithSimulation(seed),
+		locked:   IsLocked(settings),
+	}
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The steps of the valid schedules due in [from, until). Conditions
+ * on the system can't be foreseen, such jobs are assumed to run and
+ * noted as conditional.
+ * @returns (error) when stopped after SIMULATE_MAX_STEPS, along with
+ * the steps so far
+ */
+func (s *Simulator) Run(from, until time.Time) ([]SimulationStep, error) {
+	schedules := s.settings.Schedules
+	next := make(map[int]time.Time)
+	for jid := range schedules {
+		job := &schedules[jid]
+		if job.Validate(s.env) != nil || job.CheckWindow(from, s.env) != nil {
+			continue
+		}
+		if tick, err := job.NextTick(from, s.env, true); err == nil {
+			next[jid] = tick
+		}
+	}
+
+	steps := make([]SimulationStep, 0)
+	for len(steps) < SIMULATE_MAX_STEPS {
+		var at time.Time
+		due := make([]int, 0)
+		for jid := range schedules {
+			tick, ok := next[jid]
+			if !ok {
+				continue
+			}
+			if at.IsZero() || tick.Before(at) {
+				at, due = tick, []int{jid}
+			} else if tick.Equal(at) {
+				due = append(due, jid)
+			}
+		}
+		if at.IsZero() || !at.Before(until) {
+			return steps, nil
+		}
+
+		steps = append(steps, s.tick(at, due)...)
+		for jid := range next { // intervals move with every change
+			if !slices.Contains(due, jid) && !schedules[jid].IsInterval() {
+				continue
+			}
+			if tick, err := schedules[jid].NextTick(at, s.env, false); err == nil {
+				next[jid] = tick
+			} else {
+				delete(next, jid)
+			}
+		}
+	}
+	return steps, fmt.Errorf("simulation stopped after %d steps", SIMULATE_MAX_STEPS)
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The steps of the jobs due at the same time, in settings order.
+ */
+func (s *Simulator) tick(at time.Time, due []int) []SimulationStep {
+	steps := make([]SimulationStep, 0, len(due))
+	run := ResolveConflicts(s.settings.Schedules, due, s.settings.UserOptions.Conflicts)
+	for _, jid := range due {
+		job := &s.settings.Schedules[jid]
+		step := SimulationStep{Time: at, Job: jid + 1, Title: job.Title, Command: job.Command, Argument: job.Argument, Target: job.Target}
+
+		pick := false
+		switch {
+		case !slices.Contains(run, jid):
+			step.Note = SIM_NOTE_YIELDS
+		case job.Command == ActLockCarousel:
+			s.locked = true
+		case job.Command == ActUnlockCarousel:
+			s.locked = false
+		case s.locked && job.Command.ChangesWallpaper() && job.IsInterval():
+			continue // overdue every minute until unlocked, once is enough
+		case s.locked && job.Command.ChangesWallpaper():
+			step.Note = SIM_NOTE_LOCKED
+		default:
+			pick = true
+		}
+
+		if step.Note == "" {
+			s.env.MarkRun(job, at)
+		}
+
+		parts := []SimulationStep{step}
+		if pick && job.Target == TargetBoth && job.LockArgument != "" {
+			// desktop & lock screen each from its own source
+			parts = append(parts, step)
+			parts[0].Target = TargetDesktop
+			parts[1].Target, parts[1].Argument = TargetLockScreen, job.LockArgument
+		}
+		for i := range parts {
+			if pick {
+				s.preview(&parts[i], job)
+			}
+			parts[i].Locked = s.locked
+		}
+		steps = append(steps, parts...)
+	}
+	return steps
+}
+
+func (s *Simulator) preview(step *SimulationStep, job *Schedule) {
+	file, err := s.manager.AsOf(step.Time).Preview(step.Command, step.Argument)
+	step.File, step.Category, step.Carousel = file, s.manager.category, s.manager.carousel
+
+	switch {
+	case err != nil:
+		step.Note = err.Error()
+	case step.Command == ActPreviousWallpaper || step.Command == ActNextWallpaper || step.Command == ActUndoWallpaper:
+		step.Note = SIM_NOTE_HISTORY
+	case job.When != nil:
+		step.Note = SIM_NOTE_CONDITIONAL
+	}
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Write the steps of a simulation as text, JSON or CSV.
+ */
+func WriteSimulation(out io.Writer, steps []SimulationStep, format string) error {
+	switch format {
+	case SIM_FORMAT_JSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(steps)
+
+	case SIM_FORMAT_CSV:
+		writer := csv.NewWriter(out)
+		writer.Write([]string{"time", "job", "title", "action", "argument", "target", "carousel", "category", "file", "locked", "note"})
+		for _, step := range steps {
+			writer.Write([]string{
+				step.Time.Format(time.RFC3339), strconv.Itoa(step.Job), step.Title, step.Command.String(), step.Argument,
+				string(step.Target), step.Carousel, step.Category, step.File, strconv.FormatBool(step.Locked), step.Note,
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	case SIM_FORMAT_TEXT, "":
+		for _, step := range steps {
+			source := strings.Trim(step.Carousel+"/"+step.Category, "/")
+			outcome := step.File
+			if step.Note != "" {
+				note := strings.Join(strings.Fields(step.Note), " ") // errors may span lines
+				outcome = strings.TrimSpace(outcome + " (" + note + ")")
+			}
+			if _, err := fmt.Fprintf(out, "%s #%02d %-20s %-22s %-16s %s\n",
+				step.Time.Format("2006-01-02 15:04"), step.Job, step.Title, step.Command, source, outcome); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("simulation format must be %s, %s or %s, not %q", SIM_FORMAT_TEXT, SIM_FORMAT_JSON, SIM_FORMAT_CSV, format)
+}
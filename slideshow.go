@@ -0,0 +1,303 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * GNOME background XML slideshows.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SLIDESHOW_EXT        = ".xml"
+	SLIDESHOW_DURATION   = 30 * time.Minute // per picture
+	SLIDESHOW_TRANSITION = 5 * time.Second
+
+	slideStatic     = "static"
+	slideTransition = "transition"
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * A picture shown for a while (File) or the transition from one
+ * picture to the next (From, To).
+ */
+type Slide struct {
+	Duration time.Duration
+	File     string
+	From     string
+	To       string
+}
+
+/**
+ * Slides played in a loop since Start.
+ */
+type Slideshow struct {
+	Start  time.Time
+	Slides []Slide
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type xmlBackground struct {
+	XMLName   xml.Name      `xml:"background"`
+	StartTime xmlStartTime  `xml:"starttime"`
+	Slides    []xmlSlideTag `xml:",any"`
+}
+
+type xmlStartTime struct {
+	Year   int `xml:"year"`
+	Month  int `xml:"month"`
+	Day    int `xml:"day"`
+	Hour   int `xml:"hour"`
+	Minute int `xml:"minute"`
+	Second int `xml:"second"`
+}
+
+// <static> or <transition>
+type xmlSlideTag struct {
+	XMLName  xml.Name
+	Type     string        `xml:"type,attr,omitempty"`
+	Duration float64       `xml:"duration"` // seconds
+	File     *xmlSlideFile `xml:"file,omitempty"`
+	From     string        `xml:"from,omitempty"`
+	To       string        `xml:"to,omitempty"`
+}
+
+// a file name or the same picture in several sizes
+type xmlSlideFile struct {
+	Path  string `xml:",chardata"`
+	Sizes []struct {
+		Width  int    `xml:"width,attr"`
+		Height int    `xml:"height,attr"`
+		Path   string `xml:",chardata"`
+	} `xml:"size"`
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Slideshow showing every file for the given duration with
+ * a transition between them, starting now.
+ */
+func NewSlideshow(files []string, duration, transition time.Duration) *Slideshow {
+	show := &Slideshow{Start: time.Now().Truncate(time.Second), Slides: make([]Slide, 0, 2*len(files))}
+	for idx, file := range files {
+		show.Slides = append(show.Slides, Slide{Duration: duration, File: file})
+		if len(files) > 1 && transition > 0 {
+			next := files[(idx+1)%len(files)]
+			show.Slides = append(show.Slides, Slide{Duration: transition, From: file, To: next})
+		}
+	}
+	return show
+}
+
+/**
+ * (Ctor) Load a GNOME background XML slideshow. Pictures given in
+ * several sizes count as the largest one.
+ */
+func NewSlideshowFromFile(filename string) (*Slideshow, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var bg xmlBackground
+	if err = xml.Unmarshal(data, &bg); err != nil {
+		return nil, NewAppErrorf(ErrInvalidSlideshow, "%s: %s", filename, err)
+	}
+
+	st := bg.StartTime
+	show := &Slideshow{Start: time.Date(st.Year, time.Month(st.Month), st.Day, st.Hour, st.Minute, st.Second, 0, time.Local)}
+	for _, tag := range bg.Slides {
+		slide := Slide{Duration: time.Duration(tag.Duration * float64(time.Second))}
+		switch tag.XMLName.Local {
+		case slideStatic:
+			if tag.File == nil {
+				continue
+			}
+			slide.File = tag.File.largest()
+		case slideTransition:
+			slide.From, slide.To = strings.TrimSpace(tag.From), strings.TrimSpace(tag.To)
+		default:
+			continue
+		}
+		show.Slides = append(show.Slides, slide)
+	}
+
+	if show.Length() <= 0 || len(show.Files()) == 0 {
+		return nil, NewAppErrorf(ErrInvalidSlideshow, "%s has no pictures", filename)
+	}
+	return show, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * How long until the slideshow starts over.
+ */
+func (s *Slideshow) Length() time.Duration {
+	var length time.Duration
+	for _, slide := range s.Slides {
+		length += slide.Duration
+	}
+	return length
+}
+
+/**
+ * The picture the slideshow shows at the given time. Halfway through
+ * a transition it already counts as the next picture.
+ */
+func (s *Slideshow) FileAt(t time.Time) string {
+	length := s.Length()
+	if length <= 0 {
+		return ""
+	}
+
+	var elapsed time.Duration
+	if t.After(s.Start) {
+		elapsed = t.Sub(s.Start) % length
+	}
+
+	for _, slide := range s.Slides {
+		if elapsed < slide.Duration {
+			if slide.File != "" {
+				return slide.File
+			}
+			if elapsed < slide.Duration/2 {
+				return slide.From
+			}
+			return slide.To
+		}
+		elapsed -= slide.Duration
+	}
+	return s.Files()[0]
+}
+
+/**
+ * All pictures in the slideshow, in order of appearance.
+ */
+func (s *Slideshow) Files() []string {
+	files := make([]string, 0)
+	for _, slide := range s.Slides {
+		for _, file := range []string{slide.File, slide.From, slide.To} {
+			if file != "" && !slices.Contains(files, file) {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
+/**
+ * Write the slideshow as GNOME background XML.
+ */
+func (s *Slideshow) WriteXML(w io.Writer) error {
+	bg := xmlBackground{StartTime: xmlStartTime{
+		Year:   s.Start.Year(),
+		Month:  int(s.Start.Month()),
+		Day:    s.Start.Day(),
+		Hour:   s.Start.Hour(),
+		Minute: s.Start.Minute(),
+		Second: s.Start.Second(),
+	}}
+
+	for _, slide := range s.Slides {
+		tag := xmlSlideTag{Duration: math.Round(slide.Duration.Seconds()*10) / 10}
+		if slide.File != "" {
+			tag.XMLName.Local = slideStatic
+			tag.File = &xmlSlideFile{Path: slide.File}
+		} else {
+			tag.XMLName.Local = slideTransition
+			tag.Type = "overlay"
+			tag.From, tag.To = slide.From, slide.To
+		}
+		bg.Slides = append(bg.Slides, tag)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(bg); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+/**
+ * Save the slideshow as GNOME background XML.
+ */
+func (s *Slideshow) Save(filename string) error {
+	fdOut, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	err = s.WriteXML(fdOut)
+	if cerr := fdOut.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (f *xmlSlideFile) largest() string {
+	best, area := strings.TrimSpace(f.Path), -1
+	for _, size := range f.Sizes {
+		if size.Width*size.Height > area {
+			best, area = strings.TrimSpace(size.Path), size.Width*size.Height
+		}
+	}
+	return best
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the file is (most likely) a GNOME background XML slideshow.
+ */
+func isSlideshowFile(filename string) bool {
+	return strings.EqualFold(filepath.Ext(filename), SLIDESHOW_EXT)
+}
+
+/**
+ * The picture a slideshow file shows right now.
+ */
+func slideshowFrame(filename string) (string, error) {
+	show, err := NewSlideshowFromFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return show.FileAt(time.Now()), nil
+}
@@ -0,0 +1,171 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Offline solar calculator (NOAA equations), good to about a minute
+ * outside the polar circles.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SUN_SUNRISE           = "sunrise"
+	SUN_SUNSET            = "sunset"
+	SUN_NOON              = "solar_noon"
+	SUN_CIVIL_DAWN        = "civil_dawn"
+	SUN_CIVIL_DUSK        = "civil_dusk"
+	SUN_NAUTICAL_DAWN     = "nautical_dawn"
+	SUN_NAUTICAL_DUSK     = "nautical_dusk"
+	SUN_ASTRONOMICAL_DAWN = "astronomical_dawn"
+	SUN_ASTRONOMICAL_DUSK = "astronomical_dusk"
+
+	julianEpoch2000 = 2451545.0
+)
+
+// sun elevation (degrees) at each event, noon is the highest point
+var solarEvents = map[string]solarEvent{
+	SUN_SUNRISE:           {-0.833, true},
+	SUN_SUNSET:            {-0.833, false},
+	SUN_CIVIL_DAWN:        {-6, true},
+	SUN_CIVIL_DUSK:        {-6, false},
+	SUN_NAUTICAL_DAWN:     {-12, true},
+	SUN_NAUTICAL_DUSK:     {-12, false},
+	SUN_ASTRONOMICAL_DAWN: {-18, true},
+	SUN_ASTRONOMICAL_DUSK: {-18, false},
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	T y p e s
+ *-----------------------------------------------------------------*/
+
+type solarEvent struct {
+	elevation float64
+	rising    bool
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+func (g *GeoLocation) Validate() error {
+	if g.Latitude < -90 || g.Latitude > 90 || g.Longitude < -180 || g.Longitude > 180 {
+		return fmt.Errorf("location %.4f,%.4f is off the globe", g.Latitude, g.Longitude)
+	}
+	return nil
+}
+
+/**
+ * When a named solar event (sunrise, civil_dusk, solar_noon...) takes
+ * place on the given day, in the time zone of the day.
+ * @returns (bool) false if it doesn't that day (polar day or night)
+ */
+func (g *GeoLocation) EventOn(event string, day time.Time) (time.Time, bool) {
+	if event == SUN_NOON {
+		return g.solarNoon(day).In(day.Location()), true
+	}
+
+	sun, known := solarEvents[event]
+	if !known {
+		return time.Time{}, false
+	}
+	return g.CrossingOn(day, sun.elevation, sun.rising)
+}
+
+/**
+ * When the sun passes the given elevation (degrees) on the given day,
+ * in the morning (rising) or in the afternoon.
+ * @returns (bool) false if it doesn't that day
+ */
+func (g *GeoLocation) CrossingOn(day time.Time, elevation float64, rising bool) (time.Time, bool) {
+	noon := g.solarNoon(day)
+	at := noon
+	for range 3 { // the declination changes a little during the day
+		decl, _ := sunPosition(at)
+		cosH := (sinDeg(elevation) - sinDeg(g.Latitude)*sinDeg(decl)) / (cosDeg(g.Latitude) * cosDeg(decl))
+		if cosH < -1 || cosH > 1 {
+			return time.Time{}, false
+		}
+
+		offset := time.Duration(4 * degrees(math.Acos(cosH)) * float64(time.Minute))
+		if rising {
+			at = noon.Add(-offset)
+		} else {
+			at = noon.Add(offset)
+		}
+	}
+
+	return at.In(day.Location()).Truncate(time.Second), true
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Highest point of the sun on the calendar day of the given time.
+ */
+func (g *GeoLocation) solarNoon(day time.Time) time.Time {
+	y, m, d := day.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	noon := midnight.Add(time.Duration((720 - 4*g.Longitude) * float64(time.Minute)))
+	_, eqTime := sunPosition(noon)
+	return noon.Add(time.Duration(-eqTime * float64(time.Minute)))
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the name is that of a solar event we can compute.
+ */
+func IsSolarEvent(event string) bool {
+	_, known := solarEvents[event]
+	return known || event == SUN_NOON
+}
+
+/**
+ * Declination of the sun (degrees) and the equation of time (minutes)
+ * at the given instant.
+ */
+func sunPosition(t time.Time) (decl, eqTime float64) {
+	jd := float64(t.UTC().Unix())/86400 + 2440587.5
+	T := (jd - julianEpoch2000) / 36525
+
+	meanLong := math.Mod(280.46646+T*(36000.76983+T*0.0003032), 360)
+	meanAnomaly := 357.52911 + T*(35999.05029-0.0001537*T)
+	eccentricity := 0.016708634 - T*(0.000042037+0.0000001267*T)
+
+	center := sinDeg(meanAnomaly)*(1.914602-T*(0.004817+0.000014*T)) +
+		sinDeg(2*meanAnomaly)*(0.019993-0.000101*T) +
+		sinDeg(3*meanAnomaly)*0.000289
+	omega := 125.04 - 1934.136*T
+	apparentLong := meanLong + center - 0.00569 - 0.00478*sinDeg(omega)
+
+	meanObliquity := 23 + (26+(21.448-T*(46.815+T*(0.00059-T*0.001813)))/60)/60
+	obliquity := meanObliquity + 0.00256*cosDeg(omega)
+	decl = degrees(math.Asin(sinDeg(obliquity) * sinDeg(apparentLong)))
+
+	y := math.Pow(math.Tan(radians(obliquity/2)), 2)
+	eqTime = 4 * degrees(y*sinDeg(2*meanLong)-
+		2*eccentricity*sinDeg(meanAnomaly)+
+		4*eccentricity*y*sinDeg(meanAnomaly)*cosDeg(2*meanLong)-
+		0.5*y*y*sinDeg(4*meanLong)-
+		1.25*eccentricity*eccentricity*sinDeg(2*meanAnomaly))
+	return decl, eqTime
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+func sinDeg(deg float64) float64  { return math.Sin(radians(deg)) }
+func cosDeg(deg float64) float64  { return math.Cos(radians(deg)) }
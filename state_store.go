@@ -0,0 +1,265 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Persistent state store: favorites, ratings, bans & when schedules
+ * last ran.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	STATE_STORE_FILE   = "store.json"
+	FAVORITES_CATEGORY = "Favorites" // virtual category
+
+	RATING_MIN     = 1
+	RATING_MAX     = 5
+	WEIGHT_UNRATED = 3 // same as an average rating
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * What the user thinks of a wallpaper. Records are keyed by content
+ * hash so that renaming or moving the file doesn't lose them.
+ */
+type WallpaperRecord struct {
+	Path     string   `json:"path"` // last known location
+	Favorite bool     `json:"favorite,omitempty"`
+	Rating   int      `json:"rating,omitempty"` // 1..5, 0 is unrated
+	Banned   bool     `json:"banned,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type StateStore struct {
+	Wallpapers map[string]*WallpaperRecord `json:"wallpapers"`          // by content hash
+	Hashes     map[string]FileHash         `json:"hashes"`              // by path
+	Schedules  map[string]*ScheduleRecord  `json:"schedules,omitempty"` // by title
+	file       string
+}
+
+/**
+ * When a schedule was last checked (by -task or the daemon) and when
+ * it last ran. Whatever it was due to do in between was missed.
+ */
+type ScheduleRecord struct {
+	LastCheck time.Time `json:"last_check"`
+	LastRun   time.Time `json:"last_run,omitzero"`
+}
+
+/**
+ * Cached content hash of a file, valid while its size and modification
+ * time don't change.
+ */
+type FileHash struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) Load the state store from the state directory. A missing
+ * store is an empty one.
+ */
+func NewStateStore() (*StateStore, error) {
+	dir, err := GetStateDir()
+	if err != nil {
+		return nil, err
+	}
+
+	store := &StateStore{
+		Wallpapers: make(map[string]*WallpaperRecord),
+		Hashes:     make(map[string]FileHash),
+		Schedules:  make(map[string]*ScheduleRecord),
+		file:       path.Join(dir, STATE_STORE_FILE),
+	}
+
+	data, err := os.ReadFile(store.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, store); err != nil {
+		return nil, err
+	}
+	if store.Wallpapers == nil {
+		store.Wallpapers = make(map[string]*WallpaperRecord)
+	}
+	if store.Hashes == nil {
+		store.Hashes = make(map[string]FileHash)
+	}
+	if store.Schedules == nil {
+		store.Schedules = make(map[string]*ScheduleRecord)
+	}
+
+	return store, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Content hash of a wallpaper file. It is only computed when the file
+ * is new or has changed since it was last hashed.
+ */
+func (s *StateStore) HashOf(filename string) (string, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return "", err
+	}
+
+	absName, _ := filepath.Abs(filename)
+	if cached, ok := s.Hashes[absName]; ok && cached.Size == fi.Size() && cached.ModTime == fi.ModTime().UnixNano() {
+		return cached.Hash, nil
+	}
+
+	hash, err := CalculateMD5(filename)
+	if err != nil {
+		return "", err
+	}
+
+	s.Hashes[absName] = FileHash{fi.Size(), fi.ModTime().UnixNano(), hash}
+	return hash, nil
+}
+
+/**
+ * The record of a wallpaper, nil if the user never rated it.
+ */
+func (s *StateStore) Lookup(filename string) *WallpaperRecord {
+	if len(s.Wallpapers) == 0 {
+		return nil
+	}
+
+	hash, err := s.HashOf(filename)
+	if err != nil {
+		return nil
+	}
+
+	return s.LookupHash(filename, hash)
+}
+
+/**
+ * Same as Lookup() when the content hash is already known, i.e. from
+ * the catalog.
+ */
+func (s *StateStore) LookupHash(filename, hash string) *WallpaperRecord {
+	if record, ok := s.Wallpapers[hash]; ok {
+		record.Path, _ = filepath.Abs(filename)
+		return record
+	}
+	return nil
+}
+
+/**
+ * The record of a wallpaper, creating it if needed.
+ */
+func (s *StateStore) Record(filename string) (*WallpaperRecord, error) {
+	hash, err := s.HashOf(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := s.Wallpapers[hash]
+	if !ok {
+		record = &WallpaperRecord{}
+		s.Wallpapers[hash] = record
+	}
+
+	record.Path, _ = filepath.Abs(filename)
+	return record, nil
+}
+
+/**
+ * Whether there is anything that may influence selection.
+ */
+func (s *StateStore) IsEmpty() bool {
+	return len(s.Wallpapers) == 0
+}
+
+/**
+ * Favorite wallpapers that (still) exist and are not banned.
+ */
+func (s *StateStore) Favorites() []string {
+	favorites := make([]string, 0)
+	for _, record := range s.Wallpapers {
+		if record.Favorite && !record.Banned && FileExists(record.Path) {
+			favorites = append(favorites, record.Path)
+		}
+	}
+
+	return favorites
+}
+
+/**
+ * Note that the schedule was checked at the given time and whether it
+ * ran then.
+ */
+func (s *StateStore) MarkSchedule(title string, at time.Time, ran bool) {
+	record, ok := s.Schedules[title]
+	if !ok {
+		record = &ScheduleRecord{}
+		s.Schedules[title] = record
+	}
+
+	record.LastCheck = at
+	if ran {
+		record.LastRun = at
+	}
+}
+
+func (s *StateStore) Save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(s.file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.file+".tmp", s.file)
+}
+
+/**
+ * Selection weight of a wallpaper: zero when banned, its rating (or
+ * that of an average one when unrated), doubled for favorites.
+ */
+func (r *WallpaperRecord) Weight() int64 {
+	if r == nil {
+		return WEIGHT_UNRATED
+	}
+	if r.Banned {
+		return 0
+	}
+
+	weight := int64(WEIGHT_UNRATED)
+	if r.Rating >= RATING_MIN {
+		weight = int64(r.Rating)
+	}
+	if r.Favorite {
+		weight *= 2
+	}
+
+	return weight
+}
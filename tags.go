@@ -0,0 +1,226 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * Image tags from sidecar files and embedded XMP/IPTC keywords.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	SIDECAR_EXT      = ".json"   // image.jpg.json
+	XMP_SCAN_LIMIT   = 256 << 10 // XMP packets live near the start of the file
+	jpegMarkerSOI    = 0xd8
+	jpegMarkerSOS    = 0xda
+	jpegMarkerAPP13  = 0xed
+	iptcKeywordsTag  = 25 // IPTC IIM 2:25
+	photoshopIptcRes = 0x0404
+)
+
+var (
+	xmpSubjectRx = regexp.MustCompile(`(?s)<dc:subject>(.*?)</dc:subject>`)
+	xmpItemRx    = regexp.MustCompile(`(?s)<rdf:li[^>]*>(.*?)</rdf:li>`)
+)
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Content of an image sidecar file, i.e. image.jpg.json
+ */
+type Sidecar struct {
+	Tags []string `json:"tags"`
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * All tags of an image: those in its sidecar file and the XMP/IPTC
+ * keywords embedded in it. Tags are lowercase and unique.
+ */
+func ReadImageTags(filename string) []string {
+	tags := make([]string, 0)
+	tags = append(tags, readSidecarTags(filename)...)
+	tags = append(tags, readEmbeddedKeywords(filename)...)
+
+	return normalizeTags(tags)
+}
+
+/**
+ * Whether the tags satisfy a query. Plain query terms are required and
+ * those prefixed with a minus sign are excluded: [autumn, -people]
+ */
+func MatchTags(tags []string, query []string) bool {
+	for _, term := range query {
+		term = strings.ToLower(strings.TrimSpace(term))
+		if term == "" {
+			continue
+		}
+
+		if excluded, ok := strings.CutPrefix(term, "-"); ok {
+			if slices.Contains(tags, excluded) {
+				return false
+			}
+		} else if !slices.Contains(tags, term) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normalizeTags(tags []string) []string {
+	unique := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(unique, tag) {
+			unique = append(unique, tag)
+		}
+	}
+
+	return unique
+}
+
+func readSidecarTags(filename string) []string {
+	data, err := os.ReadFile(filename + SIDECAR_EXT)
+	if err != nil {
+		return nil
+	}
+
+	var sidecar Sidecar
+	if err = json.Unmarshal(data, &sidecar); err != nil {
+		return nil
+	}
+	return sidecar.Tags
+}
+
+/**
+ * Keywords embedded in the image as an XMP packet (dc:subject) and,
+ * for JPEG, as IPTC keywords.
+ */
+func readEmbeddedKeywords(filename string) []string {
+	head, err := readFileHead(filename)
+	if err != nil {
+		return nil
+	}
+	return embeddedKeywords(head)
+}
+
+func embeddedKeywords(head []byte) []string {
+	keywords := xmpKeywords(head)
+	keywords = append(keywords, iptcKeywords(head)...)
+	return keywords
+}
+
+/**
+ * The first bytes of a file, where the metadata of an image lives.
+ */
+func readFileHead(filename string) ([]byte, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return io.ReadAll(io.LimitReader(fd, XMP_SCAN_LIMIT))
+}
+
+func xmpKeywords(data []byte) []string {
+	keywords := make([]string, 0)
+	subject := xmpSubjectRx.FindSubmatch(data)
+	if subject == nil {
+		return keywords
+	}
+
+	for _, item := range xmpItemRx.FindAllSubmatch(subject[1], -1) {
+		keywords = append(keywords, string(item[1]))
+	}
+	return keywords
+}
+
+/**
+ * IPTC keywords live in the Photoshop (APP13) segment of a JPEG file.
+ */
+func iptcKeywords(data []byte) []string {
+	keywords := make([]string, 0)
+	for _, segment := range jpegSegments(data, jpegMarkerAPP13) {
+		keywords = append(keywords, photoshopIptcKeywords(segment)...)
+	}
+
+	return keywords
+}
+
+func photoshopIptcKeywords(segment []byte) []string {
+	keywords := make([]string, 0)
+	const header = "Photoshop 3.0\x00"
+	if !bytes.HasPrefix(segment, []byte(header)) {
+		return keywords
+	}
+
+	// 8BIM image resource blocks
+	res := segment[len(header):]
+	for len(res) >= 12 && bytes.HasPrefix(res, []byte("8BIM")) {
+		id := binary.BigEndian.Uint16(res[4:])
+		nameLen := int(res[6])
+		nameSize := nameLen + 1 + (nameLen+1)%2 // padded to even
+		if 6+nameSize+4 > len(res) {
+			break
+		}
+
+		dataSize := int(binary.BigEndian.Uint32(res[6+nameSize:]))
+		start := 6 + nameSize + 4
+		if start+dataSize > len(res) {
+			break
+		}
+
+		if id == photoshopIptcRes {
+			keywords = append(keywords, iptcDatasets(res[start:start+dataSize])...)
+		}
+		next := start + dataSize + dataSize%2 // padded to even, unless it is the last
+		if next > len(res) {
+			break
+		}
+		res = res[next:]
+	}
+
+	return keywords
+}
+
+/**
+ * Keywords (2:25) among the IPTC IIM datasets.
+ */
+func iptcDatasets(iim []byte) []string {
+	keywords := make([]string, 0)
+	for len(iim) >= 5 && iim[0] == 0x1c {
+		record, dataset := iim[1], iim[2]
+		size := int(binary.BigEndian.Uint16(iim[3:]))
+		if 5+size > len(iim) {
+			break
+		}
+
+		if record == 2 && dataset == iptcKeywordsTag {
+			keywords = append(keywords, string(iim[5:5+size]))
+		}
+		iim = iim[5+size:]
+	}
+
+	return keywords
+}
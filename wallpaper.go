@@ -8,13 +8,21 @@
 package carousel
 
 import (
+	"cmp"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"image/color"
 	"log"
 	"math/big"
+	mrand "math/rand/v2"
+	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 )
 
 /* ----------------------------------------------------------------
@@ -29,6 +37,13 @@ const (
 	DEFAULT_ICON      = "/home/lordofscripts/Pictures/Wallpapers/.category_icon.png" // 100x100 @audit
 )
 
+// Where a wallpaper is applied
+const (
+	TargetDesktop    WallpaperTarget = "desktop"
+	TargetLockScreen WallpaperTarget = "lockscreen"
+	TargetBoth       WallpaperTarget = "both"
+)
+
 /* ----------------------------------------------------------------
  *				I n t e r f a c e s
  *-----------------------------------------------------------------*/
@@ -68,19 +83,81 @@ type ISessionManager interface {
 	String() string
 }
 
+/**
+ * Optionally implemented by session handlers whose desktop has
+ * an accent color and/or a solid background (primary) color.
+ */
+type IAccentColorManager interface {
+	/**
+	 * Push the (dominant) color as the desktop accent color. Desktops
+	 * with a fixed set of accents use the closest one.
+	 */
+	SetAccentColor(color.RGBA) error
+
+	/**
+	 * Set the solid background color shown when the picture is
+	 * missing or doesn't cover the screen.
+	 */
+	SetPrimaryColor(color.RGBA) error
+}
+
+/**
+ * Optionally implemented by session handlers whose lock (or login)
+ * screen has a background of its own.
+ */
+type ILockScreenManager interface {
+	/**
+	 * @param (string) full path to wallpaper file
+	 * @returns (error) error if unable to set the lock screen background
+	 */
+	SetLockScreen(string) error
+}
+
+/**
+ * Optionally implemented by session handlers whose desktop plays
+ * GNOME background XML slideshows by itself.
+ */
+type ISlideshowManager interface {
+	/**
+	 * @param (string) full path to the slideshow XML file
+	 * @returns (error) error if unable to set the slideshow
+	 */
+	SetSlideshow(string) error
+}
+
 /* ----------------------------------------------------------------
  *				P u b l i c		T y p e s
  *-----------------------------------------------------------------*/
 
+// An empty target means the desktop
+type WallpaperTarget string
+
 type WallpaperManager struct {
 	settings       *Settings
 	sessionHandler ISessionManager
+	category       string // where the current pick comes from
+	carousel       string
+	schedule       string // title of the schedule that triggered us
+	target         WallpaperTarget
+	fromHistory    bool // re-applying a history entry, don't record it
+	catalog        *Catalog
+	httpClient     *http.Client // for feeds, nil for the default
+	simulated      *simulation  // dry runs, nil for the real thing
 }
 
 /* ----------------------------------------------------------------
  *				P r i v a t e	T y p e s
  *-----------------------------------------------------------------*/
 
+/**
+ * A dry run picks wallpapers as of a simulated time with reproducible
+ * randomness, without going online or saving any state.
+ */
+type simulation struct {
+	random *mrand.Rand
+	at     time.Time
+}
+
 /* ----------------------------------------------------------------
  *				I n i t i a l i z e r
  *-----------------------------------------------------------------*/
@@ -95,18 +172,144 @@ type WallpaperManager struct {
  * session manager in order to know how to set wallpapers.
  */
 func NewWallpaperMgr(settings *Settings) *WallpaperManager {
-	return &WallpaperManager{settings: settings, sessionHandler: nil}
+	return &WallpaperManager{settings: settings, sessionHandler: nil, target: TargetDesktop}
 }
 
 /* ----------------------------------------------------------------
  *				P u b l i c		M e t h o d s
  *-----------------------------------------------------------------*/
 
+/**
+ * Tell which schedule (if any) is responsible for the changes made
+ * by this manager.
+ */
+func (w *WallpaperManager) WithSchedule(title string) *WallpaperManager {
+	w.schedule = title
+	return w
+}
+
+/**
+ * Choose whether the desktop, the lock screen or both get the
+ * wallpapers set by this manager.
+ */
+func (w *WallpaperManager) WithTarget(target WallpaperTarget) *WallpaperManager {
+	if target == "" {
+		target = TargetDesktop
+	}
+	w.target = target
+	return w
+}
+
+/**
+ * Use another HTTP client for feed categories, i.e. one that talks
+ * to a local stand-in server.
+ */
+func (w *WallpaperManager) WithHTTPClient(client *http.Client) *WallpaperManager {
+	w.httpClient = client
+	return w
+}
+
+/**
+ * Pick wallpapers in a dry run: as of a simulated time (@see AsOf),
+ * with randomness from the seed and without going online or saving
+ * state. Such a manager only previews picks, it sets nothing.
+ */
+func (w *WallpaperManager) WithSimulation(seed uint64) *WallpaperManager {
+	w.simulated = &simulation{random: mrand.New(mrand.NewPCG(seed, seed)), at: time.Now()}
+	return w
+}
+
+/**
+ * Set the simulated time of a dry run.
+ */
+func (w *WallpaperManager) AsOf(at time.Time) *WallpaperManager {
+	if w.simulated != nil {
+		w.simulated.at = at
+	}
+	return w
+}
+
+/**
+ * The wallpaper an action would put up, without applying it nor
+ * asking for authorization. Actions that depend on the history or
+ * don't change the wallpaper give an empty filename.
+ */
+func (w *WallpaperManager) Preview(command Action, argument string) (string, error) {
+	w.category, w.carousel = "", ""
+	switch command {
+	case ActDefaultWallpaper:
+		return w.settings.DefaultWallpaper, nil
+
+	case ActChosenFile:
+		return argument, nil
+
+	case ActAnyWallpaper:
+		return w.pickRandomFileIn(w.settings.DefaultDir)
+
+	case ActChosenCategory:
+		return w.pickFromCategory(argument)
+
+	case ActChosenCarousel:
+		categories, exists := w.settings.Carousels[argument]
+		if !exists || len(categories) == 0 {
+			return "", NewAppErrorf(ErrUnknownCarousel, "carousel named '%s' does not exist", argument).At("carousel")
+		}
+		w.carousel = argument
+		return w.pickFromCategory(categories[w.getRandom(len(categories))])
+	}
+	return "", nil
+}
+
 /**
  * Set the wallpaper but auto-determine whether it is chosen is Light|Dark
  */
 func (w *WallpaperManager) SetWallpaperAuto(filename string) error {
-	return w.sessionHandler.SetWallpaperAuto(filename)
+	filename, err := extractWallpaper(filename)
+	if err != nil {
+		return err
+	}
+
+	// a slideshow shows the picture it would show now where the
+	// desktop can't play it by itself, and so does the lock screen.
+	picture := filename
+	if isSlideshowFile(filename) {
+		if picture, err = slideshowFrame(filename); err != nil {
+			return err
+		}
+	}
+
+	info := w.newChangeInfo(filename)
+	RunHooks(HOOK_PRE_CHANGE, w.settings.Hooks.PreChange, w.settings.Hooks.Deadline(), info)
+
+	shown := uprightImage(picture)
+	if w.target.HasDesktop() {
+		if showMgr, ok := w.sessionHandler.(ISlideshowManager); ok && isSlideshowFile(filename) {
+			err = showMgr.SetSlideshow(filename)
+		} else {
+			err = w.setWallpaperAuto(shown)
+		}
+	}
+	if err == nil && w.target.HasLockScreen() {
+		err = w.SetLockScreen(shown)
+	}
+	if err == nil {
+		w.afterChange(info)
+	}
+
+	return err
+}
+
+/**
+ * Set the background of the lock screen if the session manager has
+ * a way to do so.
+ */
+func (w *WallpaperManager) SetLockScreen(filename string) error {
+	lockMgr, ok := w.sessionHandler.(ILockScreenManager)
+	if !ok {
+		return NewAppErrorf(ErrUnsupportedTarget, "%s has no lock screen background", w.sessionHandler)
+	}
+
+	return lockMgr.SetLockScreen(filename)
 }
 
 /**
@@ -128,6 +331,7 @@ func (w *WallpaperManager) SetWallpaperLight(filename string) error {
  * directory.
  */
 func (w *WallpaperManager) SetAnyWallpaper() error {
+	w.category, w.carousel = "", ""
 	wallpaper, err := w.pickRandomFileIn(w.settings.DefaultDir)
 	if err == nil {
 		err = w.SetWallpaperAuto(wallpaper)
@@ -140,23 +344,334 @@ func (w *WallpaperManager) SetAnyWallpaper() error {
  * Set a random wallpaper from the chosen category.
  */
 func (w *WallpaperManager) SetWallpaperFromCategory(chosenCategory string) error {
+	w.carousel = ""
+	return w.setWallpaperFromCategory(chosenCategory)
+}
+
+/**
+ * If the named carousel exists in the configuration, retrieve the categories
+ * it is allowed to serve. Pick a random category from that list and then
+ * delegate the Category work to @see SetWallpaperFromCategory()
+ */
+func (w *WallpaperManager) SetWallpaperFromCarousel(chosenCarousel string) error {
+	if categories, exists := w.settings.Carousels[chosenCarousel]; exists {
+		maxItems := len(categories)
+		chosenIndex := w.getRandom(maxItems)
+		categoryName := categories[chosenIndex]
+
+		w.carousel = chosenCarousel
+		return w.setWallpaperFromCategory(categoryName)
+	}
+
+	return NewAppErrorf(ErrUnknownCarousel, "carousel named '%s' does not exist", chosenCarousel).At("carousel")
+}
+
+/**
+ * Go back to the wallpaper shown before the current one in the history.
+ */
+func (w *WallpaperManager) SetPreviousWallpaper() error {
+	history, err := NewWallpaperHistory(w.settings.UserOptions.HistorySize)
+	if err != nil {
+		return err
+	}
+
+	entry := history.Previous()
+	if entry == nil {
+		return NewWarningMsg(WarnHistoryExhausted, "no previous wallpaper in history")
+	}
+
+	if err = w.setFromHistory(entry); err != nil {
+		return err
+	}
+	return history.Save()
+}
+
+/**
+ * Pick a fresh wallpaper from the same source (carousel, category or
+ * the default directory) as the one currently shown.
+ */
+func (w *WallpaperManager) SetNextWallpaper() error {
+	history, err := NewWallpaperHistory(w.settings.UserOptions.HistorySize)
+	if err != nil {
+		return err
+	}
+
+	current := history.Current()
+	switch {
+	case current == nil:
+		return w.SetAnyWallpaper()
+	case current.Carousel != "":
+		return w.SetWallpaperFromCarousel(current.Carousel)
+	case current.Category != "":
+		return w.SetWallpaperFromCategory(current.Category)
+	}
+
+	return w.SetAnyWallpaper()
+}
+
+/**
+ * Forget the last applied wallpaper and restore the one before it.
+ */
+func (w *WallpaperManager) UndoWallpaper() error {
+	history, err := NewWallpaperHistory(w.settings.UserOptions.HistorySize)
+	if err != nil {
+		return err
+	}
+
+	entry := history.Undo()
+	if entry == nil {
+		return NewWarningMsg(WarnHistoryExhausted, "nothing to undo")
+	}
+
+	if err = w.setFromHistory(entry); err != nil {
+		return err
+	}
+	return history.Save()
+}
+
+/**
+ * Mark (or unmark) the current wallpaper as a favorite.
+ */
+func (w *WallpaperManager) FavoriteCurrent(favorite bool) error {
+	return w.updateCurrentRecord(func(r *WallpaperRecord) error {
+		r.Favorite = favorite
+		return nil
+	})
+}
+
+/**
+ * Rate the current wallpaper from 1 (meh) to 5 (love it), the
+ * higher the rating the more often it is chosen. 0 removes the rating.
+ */
+func (w *WallpaperManager) RateCurrent(rating int) error {
+	return w.updateCurrentRecord(func(r *WallpaperRecord) error {
+		if rating != 0 && (rating < RATING_MIN || rating > RATING_MAX) {
+			return fmt.Errorf("rating must be %d..%d", RATING_MIN, RATING_MAX)
+		}
+		r.Rating = rating
+		return nil
+	})
+}
+
+/**
+ * Never ever choose the current wallpaper again, and replace it with
+ * a fresh one from the same source.
+ */
+func (w *WallpaperManager) BanCurrent() error {
+	err := w.updateCurrentRecord(func(r *WallpaperRecord) error {
+		r.Banned = true
+		r.Favorite = false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.SetNextWallpaper()
+}
+
+/**
+ * Rebuild the wallpaper catalog for all configured directories.
+ */
+func (w *WallpaperManager) Reindex() (CatalogStats, error) {
+	catalog, err := w.getCatalog()
+	if err != nil {
+		return CatalogStats{}, err
+	}
+
+	err = catalog.Reindex(w.configuredDirectories())
+	if opts := w.settings.UserOptions.Duplicates; opts.Collapse {
+		catalog.Group(opts) // rather than on the next change
+	}
+	if serr := catalog.Save(); serr != nil {
+		err = errors.Join(err, serr)
+	}
+	return catalog.Stats(), err
+}
+
+/**
+ * Groups of (near-)duplicate wallpapers in all configured directories.
+ */
+func (w *WallpaperManager) FindDuplicates() ([][]CatalogEntry, error) {
+	catalog, err := w.getCatalog()
+	if err != nil {
+		return nil, err
+	}
+	defer w.saveCatalog()
+
+	return catalog.Duplicates(w.configuredDirectories(), w.settings.UserOptions.Duplicates), nil
+}
+
+/**
+ * Save the wallpapers of a category or carousel as a GNOME background
+ * XML slideshow, so that the desktop cycles through them without
+ * cron. Banned wallpapers are left out and those inside packs are
+ * extracted into the cache.
+ * @param source (string) name of a category, else of a carousel
+ * @param duration (time.Duration) how long each picture is shown
+ */
+func (w *WallpaperManager) ExportSlideshow(source, filename string, duration time.Duration) (*Slideshow, error) {
+	names := []string{source}
+	if _, exists := w.settings.Categories[source]; !exists {
+		categories, isCarousel := w.settings.Carousels[source]
+		if !isCarousel {
+			return nil, NewAppErrorf(ErrUnknownCategory, "no category or carousel named '%s'", source).At("carousel")
+		}
+		names = categories
+	}
+
+	candidates := make([]string, 0)
+	for _, name := range names {
+		category, exists := w.settings.Categories[name]
+		if !exists {
+			return nil, NewAppErrorf(ErrUnknownCategory, "category named '%s' does not exist", name).At("carousel")
+		}
+		if category.Protected && !w.authorize(category.KeyName) {
+			return nil, NewWarningMsg(WarnAuthorizationDenied, "Authorization Denied")
+		}
+
+		if category.IsSlideshow() {
+			show, err := NewSlideshowFromFile(category.Slideshow)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, show.Files()...)
+			continue
+		}
+		if category.IsDynamic() {
+			dynamic, err := NewDynamicWallpaper(category.Dynamic)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, dynamic.Files()...)
+			continue
+		}
+
+		files, err := w.candidatesOf(category)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, files...)
+	}
+
+	if opts := w.settings.UserOptions.Duplicates; opts.Collapse && w.catalog != nil {
+		candidates = w.catalog.Collapse(candidates, opts)
+		w.saveCatalog()
+	}
+
+	store, err := NewStateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if w.lookupRecord(store, candidate).Weight() == 0 {
+			continue
+		}
+
+		file, err := extractWallpaper(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if file, err = filepath.Abs(file); err != nil {
+			return nil, err
+		}
+		if !slices.Contains(files, file) {
+			files = append(files, file)
+		}
+	}
+	if err = store.Save(); err != nil { // keep the hashes we computed
+		log.Printf("could not save state: %s", err)
+	}
+
+	if len(files) == 0 {
+		return nil, NewAppErrorf(ErrNoQualifyingWallpaper, "no qualifying wallpaper files in %s", source).At("carousel")
+	}
+
+	show := NewSlideshow(files, duration, SLIDESHOW_TRANSITION)
+	return show, show.Save(filename)
+}
+
+/**
+ * Add (or remove) tags to the current wallpaper. They are kept in the
+ * state store along with those in sidecar files & image keywords.
+ */
+func (w *WallpaperManager) TagCurrent(tags []string, add bool) error {
+	return w.updateCurrentRecord(func(r *WallpaperRecord) error {
+		tags = normalizeTags(tags)
+		if add {
+			r.Tags = normalizeTags(slices.Concat(r.Tags, tags))
+		} else {
+			r.Tags = slices.DeleteFunc(r.Tags, func(tag string) bool {
+				return slices.Contains(tags, tag)
+			})
+		}
+		return nil
+	})
+}
+
+func (w *WallpaperManager) Identify() string {
+	return w.sessionHandler.String()
+}
+
+func (t WallpaperTarget) HasDesktop() bool {
+	return t == "" || t == TargetDesktop || t == TargetBoth
+}
+
+func (t WallpaperTarget) HasLockScreen() bool {
+	return t == TargetLockScreen || t == TargetBoth
+}
+
+func (t WallpaperTarget) IsValid() bool {
+	return t == "" || t == TargetDesktop || t == TargetLockScreen || t == TargetBoth
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Re-apply a wallpaper from the history keeping its original source,
+ * which needs its key device when protected.
+ */
+func (w *WallpaperManager) setFromHistory(entry *HistoryEntry) error {
+	if !FileExists(entry.Path) {
+		return NewAppErrorf(ErrMissingTarget, "wallpaper %s no longer exists", entry.Path)
+	}
+
+	category, exists := w.settings.Categories[entry.Category]
+	if !exists { // i.e. from the Favorites
+		category = w.protectionOf(entry.Path)
+	}
+	if category != nil && category.Protected {
+		if err := w.authorizeCategory(category); err != nil {
+			return err
+		}
+	}
+
+	w.category, w.carousel = entry.Category, entry.Carousel
+	w.fromHistory = true
+	defer func() { w.fromHistory = false }()
+
+	return w.SetWallpaperAuto(entry.Path)
+}
+
+func (w *WallpaperManager) setWallpaperFromCategory(chosenCategory string) error {
+	w.category = chosenCategory
+	if _, exists := w.settings.Categories[chosenCategory]; !exists && chosenCategory == FAVORITES_CATEGORY {
+		return w.setWallpaperFromFavorites()
+	}
+
 	if category, exists := w.settings.Categories[chosenCategory]; exists {
-		preAuthorized := true
 		if category.Protected {
-			preAuthorized = w.authorize(category.KeyName)
-			if !preAuthorized {
-				log.Printf("authorization denied on %s", category.KeyName)
-				if w.settings.UserOptions.Notify {
-					NotifySound()
-					NotifyAlert("Authorization denied", DEFAULT_ICON)
-				}
-
-				return NewWarningMsg(WarnAuthorizationDenied, "Authorization Denied")
+			if err := w.authorizeCategory(category); err != nil {
+				return err
 			}
 		}
 
 		// Pick a random wallpaper from the chosen category
-		if randomWallpaper, err := w.pickRandomFileIn(category.Directory); err != nil {
+		if randomWallpaper, err := w.pickFromCategory(chosenCategory); err != nil {
 			return err
 		} else {
 			err := w.SetWallpaperAuto(randomWallpaper)
@@ -178,29 +693,157 @@ func (w *WallpaperManager) SetWallpaperFromCategory(chosenCategory string) error
 }
 
 /**
- * If the named carousel exists in the configuration, retrieve the categories
- * it is allowed to serve. Pick a random category from that list and then
- * delegate the Category work to @see SetWallpaperFromCategory()
+ * The virtual Favorites category has all favorite wallpapers no matter
+ * where they live.
  */
-func (w *WallpaperManager) SetWallpaperFromCarousel(chosenCarousel string) error {
-	if categories, exists := w.settings.Carousels[chosenCarousel]; exists {
-		maxItems := len(categories)
-		chosenIndex := w.getRandom(maxItems)
-		categoryName := categories[chosenIndex]
+func (w *WallpaperManager) setWallpaperFromFavorites() error {
+	favorite, err := w.pickFromCategory(FAVORITES_CATEGORY)
+	if err != nil {
+		return err
+	}
 
-		return w.SetWallpaperFromCategory(categoryName)
+	return w.SetWallpaperAuto(favorite)
+}
+
+/**
+ * Pick a random wallpaper of the named category (or the Favorites).
+ */
+func (w *WallpaperManager) pickFromCategory(chosenCategory string) (string, error) {
+	w.category = chosenCategory
+	category, exists := w.settings.Categories[chosenCategory]
+	if !exists {
+		if chosenCategory != FAVORITES_CATEGORY {
+			return "", NewAppErrorf(ErrUnknownCategory, "category named '%s' does not exist", chosenCategory)
+		}
+
+		store, err := NewStateStore()
+		if err != nil {
+			return "", err
+		}
+		favorites, err := w.authorizedOnly(store.Favorites())
+		if err != nil {
+			return "", err
+		}
+		return w.pickWeighted(favorites)
 	}
 
-	return NewAppErrorf(ErrUnknownCarousel, "carousel named '%s' does not exist", chosenCarousel).At("carousel")
+	candidates, err := w.candidatesOf(category)
+	if err != nil {
+		return "", err
+	}
+	return w.pickWeighted(candidates)
 }
 
-func (w *WallpaperManager) Identify() string {
-	return w.sessionHandler.String()
+/**
+ * Apply a change to the state record of the wallpaper being shown.
+ */
+func (w *WallpaperManager) updateCurrentRecord(update func(*WallpaperRecord) error) error {
+	status, err := GetWallpaperStatus()
+	if err != nil {
+		return NewAppErrorWith(ErrMissingTarget, "current wallpaper unknown", err)
+	}
+
+	store, err := NewStateStore()
+	if err != nil {
+		return err
+	}
+
+	record, err := store.Record(status.Path)
+	if err != nil {
+		return err
+	}
+
+	if err = update(record); err != nil {
+		return err
+	}
+	return store.Save()
 }
 
-/* ----------------------------------------------------------------
- *				P r i v a t e	M e t h o d s
- *-----------------------------------------------------------------*/
+/**
+ * The files that don't live in a protected category or whose key
+ * device is plugged in, each device being checked once.
+ * @returns (error) WarnAuthorizationDenied when all were left out for
+ * want of their key devices
+ */
+func (w *WallpaperManager) authorizedOnly(filenames []string) ([]string, error) {
+	allowed := make(map[string]bool)
+	kept := make([]string, 0, len(filenames))
+	var denied *Category
+	for _, filename := range filenames {
+		category := w.protectionOf(filename)
+		if category != nil {
+			ok, checked := allowed[category.KeyName]
+			if !checked {
+				ok = w.authorize(category.KeyName)
+				allowed[category.KeyName] = ok
+			}
+			if !ok {
+				denied = category
+				continue
+			}
+		}
+		kept = append(kept, filename)
+	}
+
+	if len(kept) == 0 && denied != nil {
+		return kept, w.denyAuthorization(denied)
+	}
+	return kept, nil
+}
+
+/**
+ * The protected category whose directory, pack or feed the file is in,
+ * if any.
+ */
+func (w *WallpaperManager) protectionOf(filename string) *Category {
+	if archive, _, ok := splitArchivePath(filename); ok {
+		filename = archive
+	}
+
+	for _, category := range w.settings.Categories {
+		if !category.Protected {
+			continue
+		}
+
+		roots := make([]string, 0, 2)
+		if category.Directory != "" {
+			roots = append(roots, category.Directory)
+		}
+		if category.Feed != nil {
+			if dir, err := feedCacheDir(category.Feed.URL); err == nil {
+				roots = append(roots, dir)
+			}
+		}
+		for _, root := range roots {
+			rel, err := filepath.Rel(root, filename)
+			if err == nil && (rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))) {
+				return category
+			}
+		}
+	}
+	return nil
+}
+
+/**
+ * Check the key device of a protected category, telling the user when
+ * it is missing.
+ * @returns (error) WarnAuthorizationDenied unless authorized
+ */
+func (w *WallpaperManager) authorizeCategory(category *Category) error {
+	if w.authorize(category.KeyName) {
+		return nil
+	}
+	return w.denyAuthorization(category)
+}
+
+func (w *WallpaperManager) denyAuthorization(category *Category) error {
+	log.Printf("authorization denied on %s", category.KeyName)
+	if w.settings.UserOptions.Notify {
+		NotifySound()
+		NotifyAlert("Authorization denied", DEFAULT_ICON)
+	}
+	return NewWarningMsg(WarnAuthorizationDenied, "Authorization Denied")
+}
 
 func (w *WallpaperManager) authorize(deviceName string) bool {
 	if deviceName == "" {
@@ -249,10 +892,29 @@ func (w *WallpaperManager) authorize(deviceName string) bool {
 }
 
 /**
- * generate a true random integer between 0..N-1
+ * The time of the day, simulated in dry runs.
+ */
+func (w *WallpaperManager) now() time.Time {
+	if w.simulated != nil {
+		return w.simulated.at
+	}
+	return time.Now()
+}
+
+/**
+ * generate a true random integer between 0..N-1 (pseudo-random from
+ * the seed in dry runs)
  */
 func (w *WallpaperManager) getRandom(upperLimit int) int64 {
-	randomInt, err := rand.Int(rand.Reader, big.NewInt(int64(upperLimit)))
+	return w.getRandom64(int64(upperLimit))
+}
+
+func (w *WallpaperManager) getRandom64(upperLimit int64) int64 {
+	if w.simulated != nil {
+		return w.simulated.random.Int64N(upperLimit)
+	}
+
+	randomInt, err := rand.Int(rand.Reader, big.NewInt(upperLimit))
 	if err != nil {
 		log.Println("Error:", err)
 		return -1
@@ -265,42 +927,361 @@ func (w *WallpaperManager) getRandom(upperLimit int) int64 {
  * Select a random image file from the selected directory
  */
 func (w *WallpaperManager) pickRandomFileIn(dir string) (string, error) {
-	// Read the directory contents
-	files, err := os.ReadDir(dir)
+	catalog, err := w.getCatalog()
 	if err != nil {
-		fmt.Println("Error reading directory:", err)
 		return "", err
 	}
 
-	// Filter out non-regular files (like directories)
-	var regularFiles []string
-	for _, file := range files {
-		if !file.IsDir() {
-			if file.Name() == DEFAULT_ICON_FILE {
-				continue
+	candidates, err := catalog.Files(dir)
+	w.saveCatalog()
+	if err != nil {
+		return "", err
+	}
+
+	return w.pickWeighted(candidates)
+}
+
+/**
+ * Pick one of the candidate files at random. Banned wallpapers are
+ * never chosen and the user's ratings & favorites weigh the odds.
+ */
+func (w *WallpaperManager) pickWeighted(candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", NewAppErrorf(ErrNoQualifyingWallpaper, "no qualifying wallpaper files").At("carousel")
+	}
+
+	if opts := w.settings.UserOptions.Duplicates; opts.Collapse && w.catalog != nil {
+		candidates = w.catalog.Collapse(candidates, opts)
+		w.saveCatalog() // keep the groups we worked out
+	}
+
+	store, err := NewStateStore()
+	if err != nil || store.IsEmpty() {
+		// nothing to weigh, all are equally likely
+		return candidates[w.getRandom(len(candidates))], nil
+	}
+
+	weights := make([]int64, len(candidates))
+	var total int64
+	for idx, candidate := range candidates {
+		weights[idx] = w.lookupRecord(store, candidate).Weight()
+		total += weights[idx]
+	}
+	if w.simulated == nil {
+		if err = store.Save(); err != nil { // keep the hashes we computed
+			log.Printf("could not save state: %s", err)
+		}
+	}
+
+	if total == 0 {
+		return "", NewAppErrorf(ErrNoQualifyingWallpaper, "all wallpapers are banned").At("carousel")
+	}
+
+	ticket := w.getRandom64(total)
+	for idx, weight := range weights {
+		if ticket < weight {
+			return candidates[idx], nil
+		}
+		ticket -= weight
+	}
+
+	return candidates[len(candidates)-1], nil
+}
+
+/**
+ * The wallpapers a category may choose from: those in its directory
+ * or, for smart categories, those matching its query.
+ */
+func (w *WallpaperManager) candidatesOf(category *Category) ([]string, error) {
+	catalog, err := w.getCatalog()
+	if err != nil {
+		return nil, err
+	}
+	defer w.saveCatalog()
+
+	if category.IsSlideshow() {
+		show, err := NewSlideshowFromFile(category.Slideshow)
+		if err != nil {
+			return nil, err
+		}
+		return []string{show.FileAt(w.now())}, nil
+	}
+
+	if category.IsDynamic() {
+		dynamic, err := NewDynamicWallpaper(category.Dynamic)
+		if err != nil {
+			return nil, err
+		}
+		return []string{dynamic.FrameAt(w.now(), w.settings.UserOptions.Location)}, nil
+	}
+
+	if category.IsFeed() {
+		fetcher, err := NewFeedFetcher(category.Feed, w.httpClient)
+		if err != nil {
+			return nil, err
+		}
+		if w.simulated != nil { // whatever is in the cache
+			return catalog.Files(fetcher.Dir())
+		}
+		if err = fetcher.Sync(false); err != nil {
+			return nil, err
+		}
+		return catalog.Files(fetcher.Dir())
+	}
+
+	if !category.IsSmart() {
+		return catalog.Files(category.Directory)
+	}
+
+	dirs := category.Query.Directories
+	if len(dirs) == 0 {
+		dirs = w.publicDirectories()
+	}
+
+	store, err := NewStateStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.Query(dirs, category.Query, store, w.now()), nil
+}
+
+/**
+ * The state store record of a wallpaper using the content hash
+ * in the catalog if we have it, that saves a stat() per file.
+ */
+func (w *WallpaperManager) lookupRecord(store *StateStore, filename string) *WallpaperRecord {
+	if w.catalog != nil {
+		if hash, ok := w.catalog.HashOf(filename); ok {
+			return store.LookupHash(filename, hash)
+		}
+	}
+	return store.Lookup(filename)
+}
+
+/**
+ * The wallpaper catalog, loaded on first use.
+ */
+func (w *WallpaperManager) getCatalog() (*Catalog, error) {
+	if w.catalog == nil {
+		catalog, err := NewCatalog()
+		if err != nil {
+			return nil, err
+		}
+		w.catalog = catalog
+	}
+	return w.catalog, nil
+}
+
+func (w *WallpaperManager) saveCatalog() {
+	if w.catalog == nil {
+		return
+	}
+	if err := w.catalog.Save(); err != nil {
+		log.Printf("could not save catalog: %s", err)
+	}
+}
+
+/**
+ * Every directory mentioned in the configuration.
+ */
+func (w *WallpaperManager) configuredDirectories() []string {
+	dirs := []string{w.settings.DefaultDir}
+	for _, category := range w.settings.Categories {
+		candidates := []string{category.Directory}
+		if category.IsSmart() {
+			candidates = category.Query.Directories
+		} else if category.IsFeed() {
+			if dir, err := feedCacheDir(category.Feed.URL); err == nil {
+				candidates = []string{dir}
 			}
+		}
 
-			ext := strings.ToLower(path.Ext(file.Name()))
-			if ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".svg" { // @todo globalize
-				regularFiles = append(regularFiles, file.Name())
+		for _, dir := range candidates {
+			if dir != "" && !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
 			}
 		}
 	}
 
-	// Check if there are any files to choose from
-	if len(regularFiles) == 0 {
-		log.Println("No files found in the directory.")
-		return "", NewAppErrorf(ErrNoQualifyingWallpaper, "no qualifying wallpaper files").At("carousel")
+	return dirs
+}
+
+/**
+ * The default directory and those of all unprotected categories.
+ */
+func (w *WallpaperManager) publicDirectories() []string {
+	dirs := []string{w.settings.DefaultDir}
+	for _, category := range w.settings.Categories {
+		if !category.Protected && category.Directory != "" && !slices.Contains(dirs, category.Directory) {
+			dirs = append(dirs, category.Directory)
+		}
+	}
+
+	return dirs
+}
+
+func (w *WallpaperManager) setWallpaperAuto(filename string) error {
+	if w.settings.UserOptions.DarkVariant.Enabled {
+		if variant, ok := w.darkVariantFor(filename); ok {
+			// keep the original for Light mode, the variant for Dark
+			if err := w.sessionHandler.SetWallpaperLight(filename); err != nil {
+				return err
+			}
+			return w.sessionHandler.SetWallpaperDark(variant)
+		}
+	}
+
+	return w.sessionHandler.SetWallpaperAuto(filename)
+}
+
+/**
+ * Housekeeping after the wallpaper was successfully changed. Failures
+ * here are logged but never undo the change itself.
+ */
+func (w *WallpaperManager) afterChange(info *ChangeInfo) {
+	if !w.target.HasDesktop() {
+		RunHooks(HOOK_POST_CHANGE, w.settings.Hooks.PostChange, w.settings.Hooks.Deadline(), info)
+		return
+	}
+
+	if w.settings.UserOptions.Palette.Enabled {
+		if err := w.syncPalette(info); err != nil {
+			log.Printf("palette of %s: %s", info.Filename, err)
+		}
+	}
+
+	if err := NewWallpaperStatus(info).Publish(); err != nil {
+		log.Printf("could not publish current wallpaper: %s", err)
+	}
+
+	if !w.fromHistory {
+		w.recordHistory(info)
+	}
+
+	RunHooks(HOOK_POST_CHANGE, w.settings.Hooks.PostChange, w.settings.Hooks.Deadline(), info)
+}
+
+func (w *WallpaperManager) recordHistory(info *ChangeInfo) {
+	history, err := NewWallpaperHistory(w.settings.UserOptions.HistorySize)
+	if err == nil {
+		history.Record(info)
+		err = history.Save()
 	}
 
-	// Pick a random file therein
-	randomIndex := w.getRandom(len(regularFiles))
-	randomFile := regularFiles[randomIndex]
+	if err != nil {
+		log.Printf("could not record wallpaper history: %s", err)
+	}
+}
 
-	return path.Join(dir, randomFile), nil
+/**
+ * Describe the change about to happen. The color scheme is only
+ * queried when there are hooks to tell it to.
+ */
+func (w *WallpaperManager) newChangeInfo(filename string) *ChangeInfo {
+	info := &ChangeInfo{
+		Filename: filename,
+		Category: w.category,
+		Carousel: w.carousel,
+		Schedule: w.schedule,
+		Target:   string(w.target),
+	}
+
+	if len(w.settings.Hooks.PreChange) > 0 || len(w.settings.Hooks.PostChange) > 0 {
+		info.ColorScheme, _ = w.sessionHandler.QueryColorScheme()
+	}
+
+	return info
+}
+
+/**
+ * Extract the palette of the new wallpaper, publish it as JSON and
+ * optionally push its dominant color to the desktop.
+ */
+func (w *WallpaperManager) syncPalette(info *ChangeInfo) error {
+	opts := w.settings.UserOptions.Palette
+	picture := info.Filename
+	var err error
+	if isSlideshowFile(picture) {
+		if picture, err = slideshowFrame(picture); err != nil {
+			return err
+		}
+	}
+
+	palette, err := NewPaletteFromFile(picture, opts.Colors)
+	if err != nil {
+		return err
+	}
+
+	paletteFile, err := GetPaletteFile()
+	if err != nil {
+		return err
+	}
+	if err = palette.Save(paletteFile); err != nil {
+		return err
+	}
+	info.Palette, info.PaletteFile = palette, paletteFile
+
+	if !opts.SyncAccent && !opts.SyncFallback {
+		return nil
+	}
+
+	accentMgr, ok := w.sessionHandler.(IAccentColorManager)
+	if !ok {
+		return fmt.Errorf("%s has no accent colors", w.sessionHandler)
+	}
+
+	dominant := palette.DominantColor()
+	if opts.SyncAccent {
+		if err = accentMgr.SetAccentColor(dominant); err != nil {
+			return err
+		}
+	}
+	if opts.SyncFallback {
+		err = accentMgr.SetPrimaryColor(dominant)
+	}
+
+	return err
+}
+
+/**
+ * When the desktop is in Dark mode and the wallpaper is bright, get
+ * (or synthesize) its dark variant. Any failure (i.e. an SVG we can't
+ * decode) simply means there is no variant.
+ */
+func (w *WallpaperManager) darkVariantFor(filename string) (string, bool) {
+	opts := w.settings.UserOptions.DarkVariant
+	// a configuration that only enables it gets the defaults
+	opts.Threshold = cmp.Or(opts.Threshold, DefaultDarkVariantOpts.Threshold)
+	opts.Dim = cmp.Or(opts.Dim, DefaultDarkVariantOpts.Dim)
+	opts.Desaturate = cmp.Or(opts.Desaturate, DefaultDarkVariantOpts.Desaturate)
+
+	scheme, err := w.sessionHandler.QueryColorScheme()
+	if err != nil || !isDarkScheme(scheme) {
+		return "", false
+	}
+
+	if variant, ok := lookupDarkVariant(filename, opts); ok {
+		return variant, true
+	}
+	if bright, err := isBrightImage(filename, opts.Threshold); err != nil || !bright {
+		return "", false
+	}
+
+	variant, err := getDarkVariant(filename, opts)
+	if err != nil {
+		log.Printf("no dark variant for %s: %s", filename, err)
+		return "", false
+	}
+
+	return variant, true
 }
 
 func (w *WallpaperManager) getIcon(dir string) string {
+	if dir == "" { // smart categories
+		return ""
+	}
+
 	filename := path.Join(dir, DEFAULT_ICON_FILE)
 	_, err := os.Stat(filename)
 	//return !errors.Is(err, os.ErrNotExist)
@@ -314,3 +1295,21 @@ func (w *WallpaperManager) getIcon(dir string) string {
 /* ----------------------------------------------------------------
  *					F u n c t i o n s
  *-----------------------------------------------------------------*/
+
+/**
+ * Photos taken with the camera sideways are shown upright by using
+ * a rotated copy. Anything else is shown as is.
+ */
+func uprightImage(filename string) string {
+	orientation := ReadExif(filename).Orientation
+	if orientation == ORIENT_NORMAL {
+		return filename
+	}
+
+	upright, err := getUprightVariant(filename, orientation)
+	if err != nil {
+		log.Printf("could not rotate %s: %s", filename, err)
+		return filename
+	}
+	return upright
+}
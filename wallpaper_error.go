@@ -24,6 +24,11 @@ const (
 	ErrUnknownCarousel
 	ErrUnknownCategory
 	ErrUnknownSessionManager
+	ErrUnsupportedTarget
+	ErrInvalidQuery
+	ErrInvalidFeed
+	ErrInvalidSlideshow
+	ErrInvalidDynamic
 )
 
 /* ----------------------------------------------------------------
@@ -91,7 +96,7 @@ func (e *AppErrorBase[T]) Error() string {
 	if p := strings.Index(t, "."); p != -1 {
 		t = t[p:]
 	}
-	return fmt.Sprintf("(%s) #E%03d %s\n\t%s %s", t, e.errnum, e.errnum, e.location, e.message)
+	return fmt.Sprintf("(%s) #E%03d %v\n\t%s %s", t, e.errnum, e.errnum, e.location, e.message)
 }
 
 /**
@@ -115,6 +120,11 @@ func (n ApplicationErrorCode) String() string {
 		ErrUnknownCarousel:       "ErrUnknownCarousel",
 		ErrUnknownCategory:       "ErrUnknownCategory",
 		ErrUnknownSessionManager: "ErrUnknownSessionManager",
+		ErrUnsupportedTarget:     "ErrUnsupportedTarget",
+		ErrInvalidQuery:          "ErrInvalidQuery",
+		ErrInvalidFeed:           "ErrInvalidFeed",
+		ErrInvalidSlideshow:      "ErrInvalidSlideshow",
+		ErrInvalidDynamic:        "ErrInvalidDynamic",
 	}
 	return toString[n]
 }
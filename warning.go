@@ -17,6 +17,8 @@ import "fmt"
 const (
 	WarnEmpty WarningCode = iota
 	WarnAuthorizationDenied
+	WarnHistoryExhausted
+	WarnCarouselLocked
 )
 
 /* ----------------------------------------------------------------
@@ -0,0 +1,304 @@
+/* -----------------------------------------------------------------
+ *					L o r d  O f   S c r i p t s (tm)
+ *				  Copyright (C)2025 Dídimo Grimaldo T.
+ *							go-carousel
+ * - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -
+ * The days on which a schedule is active: a date range, months or
+ * seasons and yearly ranges such as Dec 1 – Jan 6.
+ *-----------------------------------------------------------------*/
+package carousel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* ----------------------------------------------------------------
+ *						G l o b a l s
+ *-----------------------------------------------------------------*/
+
+const (
+	WINDOW_DATE_FORMAT   = "2006-01-02"
+	WINDOW_YEARLY_FORMAT = "01-02"
+	YEARLY_SEPARATOR     = ".."
+	WINDOW_CHECK_DAYS    = 4 * 366 // long enough for Feb 29
+)
+
+// meteorological seasons of the northern hemisphere
+var seasons = map[string][]time.Month{
+	"spring": {time.March, time.April, time.May},
+	"summer": {time.June, time.July, time.August},
+	"autumn": {time.September, time.October, time.November},
+	"fall":   {time.September, time.October, time.November},
+	"winter": {time.December, time.January, time.February},
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		T y p e s
+ *-----------------------------------------------------------------*/
+
+/**
+ * The days on which a schedule is active. All conditions must hold,
+ * those not given always do. Dates are compared as YYYYMMDD and
+ * yearly dates as MMDD numbers.
+ */
+type ScheduleWindow struct {
+	From   int // 0 when open
+	Until  int // 0 when open
+	Months [12]bool
+	Yearly []YearlyRange
+
+	anyMonth bool
+}
+
+/**
+ * A range of days repeating every year, it wraps around the new year
+ * when it ends before it starts.
+ */
+type YearlyRange struct {
+	From  int
+	Until int
+}
+
+/* ----------------------------------------------------------------
+ *				C o n s t r u c t o r s
+ *-----------------------------------------------------------------*/
+
+/**
+ * (Ctor) The active window of a schedule. Seasons are flipped in the
+ * southern hemisphere when the options have a location there.
+ */
+func NewScheduleWindow(s *Schedule, where *GeoLocation) (*ScheduleWindow, error) {
+	window := &ScheduleWindow{}
+
+	var err error
+	if window.From, err = parseWindowDate(s.ActiveFrom); err != nil {
+		return nil, fmt.Errorf("active_from: %w", err)
+	}
+	if window.Until, err = parseWindowDate(s.ActiveUntil); err != nil {
+		return nil, fmt.Errorf("active_until: %w", err)
+	}
+	if window.From != 0 && window.Until != 0 && window.Until < window.From {
+		return nil, fmt.Errorf("active_until %s is before active_from %s", s.ActiveUntil, s.ActiveFrom)
+	}
+
+	southern := where != nil && where.Latitude < 0
+	for _, name := range s.Months {
+		months, err := parseMonths(name, southern)
+		if err != nil {
+			return nil, err
+		}
+		for _, month := range months {
+			window.Months[month-1] = true
+		}
+		window.anyMonth = true
+	}
+
+	for _, spec := range s.Yearly {
+		yearly, err := ParseYearlyRange(spec)
+		if err != nil {
+			return nil, err
+		}
+		window.Yearly = append(window.Yearly, yearly)
+	}
+	return window, nil
+}
+
+/**
+ * (Ctor) Parse MM-DD..MM-DD, i.e. 12-01..01-06
+ */
+func ParseYearlyRange(spec string) (YearlyRange, error) {
+	from, until, ok := strings.Cut(strings.TrimSpace(spec), YEARLY_SEPARATOR)
+	if !ok {
+		return YearlyRange{}, fmt.Errorf("yearly range must be MM-DD..MM-DD, not %q", spec)
+	}
+
+	var yearly YearlyRange
+	for _, part := range []struct {
+		text string
+		into *int
+	}{{from, &yearly.From}, {until, &yearly.Until}} {
+		day, err := time.Parse(WINDOW_YEARLY_FORMAT, strings.TrimSpace(part.text))
+		if err != nil {
+			return yearly, fmt.Errorf("yearly range %q: bad date %q", spec, part.text)
+		}
+		*part.into = int(day.Month())*100 + day.Day()
+	}
+	return yearly, nil
+}
+
+/* ----------------------------------------------------------------
+ *				P u b l i c		M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the window is open on the day of the given time.
+ */
+func (w *ScheduleWindow) Contains(day time.Time) bool {
+	y, m, d := day.Date()
+	date := y*10000 + int(m)*100 + d
+	if (w.From != 0 && date < w.From) || (w.Until != 0 && date > w.Until) {
+		return false
+	}
+	if w.anyMonth && !w.Months[m-1] {
+		return false
+	}
+
+	if len(w.Yearly) == 0 {
+		return true
+	}
+	for _, yearly := range w.Yearly {
+		if yearly.Contains(date % 10000) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Midnight of the first day after the given one on which the window
+ * is open.
+ * @returns (bool) false when the window never opens again
+ */
+func (w *ScheduleWindow) NextOpen(after time.Time) (time.Time, bool) {
+	y, m, d := after.Date()
+	day := time.Date(y, m, d+1, 0, 0, 0, 0, after.Location())
+	if w.From != 0 {
+		from := time.Date(w.From/10000, time.Month(w.From/100%100), w.From%100, 0, 0, 0, 0, after.Location())
+		if from.After(day) {
+			day = from
+		}
+	}
+
+	for i := 0; i < WINDOW_CHECK_DAYS; i++ {
+		if w.Contains(day) {
+			return day, true
+		}
+		if w.ended(day) {
+			break
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}, false
+}
+
+/**
+ * Whether a MMDD day falls in the range.
+ */
+func (r YearlyRange) Contains(monthDay int) bool {
+	if r.From <= r.Until {
+		return monthDay >= r.From && monthDay <= r.Until
+	}
+	return monthDay >= r.From || monthDay <= r.Until
+}
+
+func (r YearlyRange) String() string {
+	return fmt.Sprintf("%02d-%02d%s%02d-%02d", r.From/100, r.From%100, YEARLY_SEPARATOR, r.Until/100, r.Until%100)
+}
+
+/**
+ * Whether the schedule has an active window at all.
+ */
+func (s *Schedule) HasWindow() bool {
+	return s.ActiveFrom != "" || s.ActiveUntil != "" || len(s.Months) > 0 || len(s.Yearly) > 0
+}
+
+/**
+ * Check that the active window of the schedule is still to come and
+ * has days on which its cron expression (or solar trigger) fires.
+ */
+func (s *Schedule) CheckWindow(now time.Time, env *ScheduleEnv) error {
+	if !s.HasWindow() {
+		return nil
+	}
+
+	window, err := NewScheduleWindow(s, env.Location)
+	if err != nil {
+		return err
+	}
+
+	day := now
+	if !window.Contains(day) {
+		if day, err = s.windowOpen(window, now); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < WINDOW_CHECK_DAYS; i++ {
+		if window.ended(day) {
+			break
+		}
+		if window.Contains(day) && s.onDay(day) {
+			return nil
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return fmt.Errorf("active window never meets cron expression %q", s.CronTab)
+}
+
+/* ----------------------------------------------------------------
+ *				P r i v a t e	M e t h o d s
+ *-----------------------------------------------------------------*/
+
+/**
+ * Whether the window closed for good before the given day.
+ */
+func (w *ScheduleWindow) ended(day time.Time) bool {
+	y, m, d := day.Date()
+	return w.Until != 0 && y*10000+int(m)*100+d > w.Until
+}
+
+func (s *Schedule) windowOpen(window *ScheduleWindow, after time.Time) (time.Time, error) {
+	day, ok := window.NextOpen(after)
+	if !ok {
+		if window.ended(after) {
+			return day, fmt.Errorf("active window ended on %s", s.ActiveUntil)
+		}
+		return day, fmt.Errorf("active window never opens")
+	}
+	return day, nil
+}
+
+/* ----------------------------------------------------------------
+ *					F u n c t i o n s
+ *-----------------------------------------------------------------*/
+
+/**
+ * A YYYY-MM-DD date as a YYYYMMDD number, 0 when empty.
+ */
+func parseWindowDate(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+
+	day, err := time.Parse(WINDOW_DATE_FORMAT, strings.TrimSpace(text))
+	if err != nil {
+		return 0, fmt.Errorf("date must be YYYY-MM-DD, not %q", text)
+	}
+	return day.Year()*10000 + int(day.Month())*100 + day.Day(), nil
+}
+
+/**
+ * The months named by a month (full or abbreviated) or a season.
+ */
+func parseMonths(name string, southern bool) ([]time.Month, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if months, ok := seasons[name]; ok {
+		if !southern {
+			return months, nil
+		}
+		flipped := make([]time.Month, len(months))
+		for i, month := range months {
+			flipped[i] = (month+5)%12 + 1
+		}
+		return flipped, nil
+	}
+
+	for month := time.January; month <= time.December; month++ {
+		full := strings.ToLower(month.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return []time.Month{month}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown month or season %q", name)
+}